@@ -0,0 +1,123 @@
+package fastjson
+
+import (
+	"io"
+	"sync"
+)
+
+// Config collects the options of an API. It is a plain value that can be
+// copied and adjusted freely; Freeze turns it into an API ready for use.
+type Config struct {
+	Encode EncodeOptions
+	Decode DecodeOptions
+
+	// NamingStrategy names struct fields whose json tag does not, both when
+	// encoding and decoding. Nil keeps the Go field name.
+	NamingStrategy NamingStrategy
+
+	// IgnoreMarshalers compiles every type by reflection, even those with
+	// MarshalFastJSON or UnmarshalFastJSON methods. It gives a reference
+	// to check generated methods against.
+	IgnoreMarshalers bool
+}
+
+// API is a frozen Config. Each API compiles and caches its own codecs, so
+// APIs never observe each other's settings. An API is safe for concurrent
+// use and should be created once and reused: the first call for each type
+// pays for compiling its codec.
+type API struct {
+	encodeOpts EncodeOptions
+	decodeOpts DecodeOptions
+	naming     NamingStrategy
+
+	ignoreMarshalers bool
+
+	// shared names the package-level variable holding a, if any.
+	// Registering codecs on it would change it for every package that
+	// uses it, so registration panics instead.
+	shared string
+
+	encoders sync.Map // reflect.Type -> cachedEncoder
+	decoders sync.Map // reflect.Type -> cachedDecoder
+	codecs   typeCodecs
+}
+
+// defaultAPI backs the package-level functions.
+var defaultAPI = &API{}
+
+// The APIs below are shared by every package that uses them, so codecs and
+// unions cannot be registered on them: freeze their Config() into an API of
+// your own for that.
+var (
+	// ConfigCompatibleWithStandardLibrary behaves like encoding/json: HTML
+	// characters are escaped, map keys are sorted, invalid UTF-8 is replaced
+	// with U+FFFD and input must be valid JSON with nothing after the value.
+	ConfigCompatibleWithStandardLibrary = sharedAPI("ConfigCompatibleWithStandardLibrary", Config{
+		Encode: EncodeOptions{
+			EscapeHTML:  true,
+			SortMapKeys: true,
+			InvalidUTF8: UTF8Replace,
+		},
+		Decode: DecodeOptions{
+			Strict:      true,
+			InvalidUTF8: UTF8Replace,
+		},
+	})
+
+	// ConfigFastest skips every optional check and transformation, like the
+	// package-level functions.
+	ConfigFastest = sharedAPI("ConfigFastest", Config{})
+)
+
+func sharedAPI(name string, c Config) *API {
+	a := c.Freeze()
+	a.shared = name
+	return a
+}
+
+// Freeze returns an API with c's options and empty codec caches.
+func (c Config) Freeze() *API {
+	return &API{
+		encodeOpts:       c.Encode,
+		decodeOpts:       c.Decode,
+		naming:           c.NamingStrategy,
+		ignoreMarshalers: c.IgnoreMarshalers,
+	}
+}
+
+// Config returns the options a was frozen with.
+func (a *API) Config() Config {
+	return Config{
+		Encode:           a.encodeOpts,
+		Decode:           a.decodeOpts,
+		NamingStrategy:   a.naming,
+		IgnoreMarshalers: a.ignoreMarshalers,
+	}
+}
+
+// Marshal returns the JSON encoding of v.
+func (a *API) Marshal(v any) ([]byte, error) {
+	return a.marshal(v, a.encodeOpts)
+}
+
+// MarshalIndent is like Marshal but indents the output; see MarshalIndent.
+func (a *API) MarshalIndent(v any, prefix, indent string) ([]byte, error) {
+	opts := a.encodeOpts
+	opts.Prefix, opts.Indent = prefix, indent
+	return a.marshal(v, opts)
+}
+
+// Unmarshal parses data and stores the result in the value pointed to by v.
+func (a *API) Unmarshal(data []byte, v any) error {
+	return a.unmarshal(data, v, a.decodeOpts)
+}
+
+// NewEncoder returns an Encoder writing to w with a's options.
+func (a *API) NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{api: a, w: w, opts: a.encodeOpts}
+}
+
+// NewDecoder returns a Decoder reading from r with a's options.
+func (a *API) NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{api: a, r: r, opts: a.decodeOpts}
+}
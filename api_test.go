@@ -0,0 +1,261 @@
+package fastjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestAPI_CompatibleWithStandardLibrary(t *testing.T) {
+	input := map[string]any{
+		"z": "<a&b>",
+		"a": []any{1.5, "x\u2028y", nil},
+		"m": map[string]any{"b": true, "a": false},
+	}
+
+	expected, err := json.Marshal(input)
+	if err != nil {
+		t.Fatalf("json.Marshal failed: %v", err)
+	}
+	got, err := ConfigCompatibleWithStandardLibrary.Marshal(input)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if string(got) != string(expected) {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+
+	// The default API is unaffected.
+	got, err = Marshal(map[string]string{"k": "<>"})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if string(got) != `{"k":"<>"}` {
+		t.Errorf("default API escaped HTML: %s", got)
+	}
+
+	var u User
+	if err := ConfigCompatibleWithStandardLibrary.Unmarshal([]byte(`{"id": 1} x`), &u); err == nil {
+		t.Error("expected trailing data to be rejected")
+	}
+}
+
+func TestAPI_SharedRejectsRegistration(t *testing.T) {
+	for name, api := range map[string]*API{
+		"ConfigCompatibleWithStandardLibrary": ConfigCompatibleWithStandardLibrary,
+		"ConfigFastest":                       ConfigFastest,
+	} {
+		func() {
+			defer func() {
+				if r := recover(); r == nil || !strings.Contains(fmt.Sprint(r), name) {
+					t.Errorf("%s: RegisterTypeEncoder did not panic naming it: %v", name, r)
+				}
+			}()
+			api.RegisterTypeEncoder(centsType, encodeCents)
+		}()
+	}
+
+	// A copy of their Config can be registered on.
+	own := ConfigFastest.Config().Freeze()
+	own.RegisterTypeEncoder(centsType, encodeCents)
+	if got, _ := own.Marshal(cents(150)); string(got) != `"1.50"` {
+		t.Errorf("Marshal = %s", got)
+	}
+	if got, _ := ConfigFastest.Marshal(cents(150)); string(got) != `150` {
+		t.Errorf("shared API changed: %s", got)
+	}
+}
+
+func TestAPI_IsolatedCaches(t *testing.T) {
+	a := Config{}.Freeze()
+	b := Config{}.Freeze()
+	if _, err := a.Marshal(User{}); err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	userType := reflect.TypeFor[User]()
+	if _, ok := a.encoders.Load(userType); !ok {
+		t.Error("expected a to cache the User encoder")
+	}
+	if _, ok := b.encoders.Load(userType); ok {
+		t.Error("expected b's cache to be empty")
+	}
+}
+
+func TestUnmarshal_DisallowUnknownFields(t *testing.T) {
+	input := []byte(`{"id": 1, "nickname": "x"}`)
+
+	var u User
+	if err := Unmarshal(input, &u); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	err := UnmarshalWithOptions(input, &u, DecodeOptions{DisallowUnknownFields: true})
+	var fieldErr *UnknownFieldError
+	if !errors.As(err, &fieldErr) {
+		t.Fatalf("expected *UnknownFieldError, got %v", err)
+	}
+	if fieldErr.Key != "nickname" || fieldErr.Struct != "User" || fieldErr.Offset != 10 {
+		t.Errorf("unexpected error fields: %+v", fieldErr)
+	}
+}
+
+func TestUnmarshal_UseNumber(t *testing.T) {
+	var v any
+	err := UnmarshalWithOptions([]byte(`{"big": 12345678901234567890, "f": 1.50}`), &v, DecodeOptions{UseNumber: true})
+	if err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	m := v.(map[string]any)
+	if m["big"] != Number("12345678901234567890") {
+		t.Errorf("expected Number, got %#v", m["big"])
+	}
+	if m["f"] != Number("1.50") {
+		t.Errorf("expected Number, got %#v", m["f"])
+	}
+}
+
+func TestNumber_RoundTrip(t *testing.T) {
+	type Payload struct {
+		N Number `json:"n"`
+	}
+
+	var p Payload
+	if err := Unmarshal([]byte(`{"n": -1.0e+10}`), &p); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if p.N != "-1.0e+10" {
+		t.Errorf("expected text preserved, got %q", p.N)
+	}
+
+	got, err := Marshal(p)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if string(got) != `{"n":-1.0e+10}` {
+		t.Errorf("unexpected output %s", got)
+	}
+
+	if _, err := Marshal(Payload{N: "12abc"}); err == nil {
+		t.Error("expected invalid Number to be rejected")
+	}
+}
+
+func TestMarshal_SortMapKeys(t *testing.T) {
+	m := map[string]int{"c": 3, "a": 1, "b": 2}
+	got, err := MarshalWithOptions(m, EncodeOptions{SortMapKeys: true})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if string(got) != `{"a":1,"b":2,"c":3}` {
+		t.Errorf("unexpected output %s", got)
+	}
+}
+
+func TestMarshal_PointerInInterface(t *testing.T) {
+	x := 42
+	got, err := Marshal(Generic{Data: &x})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if string(got) != `{"data":42}` {
+		t.Errorf("unexpected output %s", got)
+	}
+
+	got, err = Marshal(map[string]*int{"x": &x, "nil": nil})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if string(got) != `{"x":42,"nil":null}` && string(got) != `{"nil":null,"x":42}` {
+		t.Errorf("unexpected output %s", got)
+	}
+}
+
+func TestDecoder_Stream(t *testing.T) {
+	input := ` {"id": 1, "name": "a"}{"id": 2}
+	[1, 2] 3.25 "s" true null 17`
+
+	// Reading one byte at a time cuts every token at a read boundary.
+	dec := NewDecoder(iotest.OneByteReader(strings.NewReader(input)))
+
+	var u1, u2 User
+	var arr []int
+	var f float64
+	var s string
+	var b bool
+	var n any = "x"
+	var last int
+	for _, v := range []any{&u1, &u2, &arr, &f, &s, &b, &n, &last} {
+		if !dec.More() {
+			t.Fatal("expected more values")
+		}
+		if err := dec.Decode(v); err != nil {
+			t.Fatalf("Decode failed: %v", err)
+		}
+	}
+
+	if u1.ID != 1 || u1.Name != "a" || u2.ID != 2 || len(arr) != 2 || f != 3.25 ||
+		s != "s" || !b || n != nil || last != 17 {
+		t.Errorf("unexpected values: %+v %+v %v %v %q %v %v %v", u1, u2, arr, f, s, b, n, last)
+	}
+
+	if dec.More() {
+		t.Error("expected no more values")
+	}
+	if err := dec.Decode(&last); err != io.EOF {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+}
+
+func TestDecoder_Errors(t *testing.T) {
+	dec := NewDecoder(strings.NewReader(`{"id": 1} {"id": tru}`))
+	var u User
+	if err := dec.Decode(&u); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	var syntaxErr *SyntaxError
+	if err := dec.Decode(&u); !errors.As(err, &syntaxErr) {
+		t.Errorf("expected *SyntaxError, got %v", err)
+	}
+
+	dec = NewDecoder(strings.NewReader(`{"id": 1, "extra": 2}`))
+	dec.DisallowUnknownFields()
+	var fieldErr *UnknownFieldError
+	if err := dec.Decode(&u); !errors.As(err, &fieldErr) {
+		t.Errorf("expected *UnknownFieldError, got %v", err)
+	}
+
+	api := Config{Decode: DecodeOptions{MaxInputSize: 16}}.Freeze()
+	dec = api.NewDecoder(strings.NewReader(`[` + strings.Repeat(`1,`, 100) + `1]`))
+	var limitErr *LimitError
+	if err := dec.Decode(&u); !errors.As(err, &limitErr) {
+		t.Errorf("expected *LimitError, got %v", err)
+	}
+}
+
+func TestEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	if err := enc.Encode(User{ID: 1}); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	enc.SetEscapeHTML(true)
+	enc.SetIndent("", " ")
+	if err := enc.Encode([]string{"<"}); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	expected := "{\"id\":1,\"name\":\"\",\"is_active\":false,\"Balance\":0}\n[\n \"\\u003c\"\n]\n"
+	if buf.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, buf.String())
+	}
+}
@@ -0,0 +1,717 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/HeartBeat1608/fastjson"
+	"github.com/HeartBeat1608/fastjson/internal/structtag"
+)
+
+const header = "// Code generated by fastjson-gen. DO NOT EDIT.\n\n"
+
+type generator struct {
+	pkg       *types.Package
+	structs   []*types.Named
+	generated map[*types.Named]bool
+	opts      options
+
+	buf     bytes.Buffer
+	imports map[string]string // path -> name, for the test's samples
+}
+
+func newGenerator(pkg *types.Package, structs []*types.Named, opts options) *generator {
+	g := &generator{pkg: pkg, structs: structs, opts: opts, generated: make(map[*types.Named]bool)}
+	for _, named := range structs {
+		g.generated[named] = true
+	}
+	return g
+}
+
+func (g *generator) p(format string, args ...any) {
+	fmt.Fprintf(&g.buf, format, args...)
+	g.buf.WriteByte('\n')
+}
+
+// field is a struct field that is encoded and decoded.
+type field struct {
+	index int // in the struct, skipped fields included
+	name  string
+	typ   types.Type
+	tag   structtag.Field
+}
+
+func (g *generator) fields(named *types.Named) []field {
+	return g.structFields(named.Underlying().(*types.Struct))
+}
+
+func (g *generator) structFields(st *types.Struct) []field {
+	var fields []field
+	for i := range st.NumFields() {
+		v := st.Field(i)
+		tag := structtag.Parse(v.Name(), reflect.StructTag(st.Tag(i)), g.opts.naming)
+		if tag.Skip {
+			continue
+		}
+		fields = append(fields, field{index: i, name: v.Name(), typ: v.Type(), tag: tag})
+	}
+	return fields
+}
+
+// methods returns the source of the file holding the generated methods.
+func (g *generator) methods() ([]byte, error) {
+	g.buf.Reset()
+	g.buf.WriteString(header)
+	g.p("package %s", g.pkg.Name())
+	g.p("")
+	g.p("import (")
+	g.p("%q", "reflect")
+	g.p("")
+	g.p("%q", "github.com/HeartBeat1608/fastjson")
+	g.p(")")
+
+	for _, named := range g.structs {
+		fields := g.fields(named)
+		g.marshal(named, fields)
+		if err := g.unmarshal(named, fields); err != nil {
+			return nil, err
+		}
+	}
+	return g.buf.Bytes(), nil
+}
+
+func (g *generator) marshal(named *types.Named, fields []field) {
+	name := named.Obj().Name()
+	g.p("")
+	g.p("// MarshalFastJSON implements fastjson.Marshaler.")
+	g.p("func (x *%s) MarshalFastJSON(w *fastjson.Writer) error {", name)
+	if len(fields) == 0 {
+		g.p("w.WriteString(\"{}\")")
+		g.p("return nil")
+		g.p("}")
+		return
+	}
+
+	g.p("w.ObjectStart()")
+	for i, f := range fields {
+		// Like the compiled encoders, keys are only escaped at run time
+		// when they have characters that the Writer's options may escape.
+		if plainKey(f.tag.Name) {
+			g.p("w.ObjectKey(%s, %t)", quoteKey(f.tag.Name), i == 0)
+		} else {
+			g.p("w.ObjectKeyEscaped(%s, %t)", strconv.Quote(f.tag.Name), i == 0)
+		}
+		expr := "x." + f.name
+		if f.tag.Quoted && isQuotable(f.typ) {
+			g.p("w.WriteByte('\"')")
+			g.encodeValue(expr, f.typ, false)
+			g.p("w.WriteByte('\"')")
+		} else {
+			g.encodeValue(expr, f.typ, true)
+		}
+	}
+	g.p("w.ObjectEnd()")
+	g.p("return nil")
+	g.p("}")
+}
+
+// plainKey reports whether key reads the same under every escaping option
+// of the Writer: it has no quotes, backslashes, control characters, HTML
+// characters or non-ASCII bytes.
+func plainKey(key string) bool {
+	for i := 0; i < len(key); i++ {
+		switch c := key[i]; {
+		case c < 0x20, c >= utf8.RuneSelf, c == '"', c == '\\', c == '<', c == '>', c == '&':
+			return false
+		}
+	}
+	return true
+}
+
+// quoteKey returns the Go literal of key in JSON quotes, preferring a raw
+// string.
+func quoteKey(key string) string {
+	if strconv.CanBackquote(key) {
+		return "`\"" + key + "\"`"
+	}
+	return strconv.Quote(`"` + key + `"`)
+}
+
+// encodeValue writes the statements encoding expr, an addressable
+// expression of type t. Slices are only unrolled when nested is set.
+func (g *generator) encodeValue(expr string, t types.Type, nested bool) {
+	if b, ok := predeclared(t); ok {
+		switch b.Kind() {
+		case types.String:
+			g.p("w.WriteStringEscaped(%s)", expr)
+		case types.Int, types.Int32:
+			g.p("w.WriteInt64(int64(%s))", expr)
+		case types.Int64:
+			g.p("w.WriteInt64(%s)", expr)
+		case types.Float64:
+			g.p("if err := w.EncodeFloat64(%s); err != nil {", expr)
+			g.p("return err")
+			g.p("}")
+		case types.Bool:
+			g.p("w.WriteBool(%s)", expr)
+		}
+		return
+	}
+
+	switch t := t.(type) {
+	case *types.Named:
+		if g.generated[t] {
+			g.p("if err := %s.MarshalFastJSON(w); err != nil {", expr)
+			g.p("return err")
+			g.p("}")
+			return
+		}
+	case *types.Pointer:
+		if named, ok := t.Elem().(*types.Named); ok && g.generated[named] {
+			g.p("if %s == nil {", expr)
+			g.p("w.WriteNull()")
+			g.p("} else if err := %s.MarshalFastJSON(w); err != nil {", expr)
+			g.p("return err")
+			g.p("}")
+			return
+		}
+	case *types.Slice:
+		if nested && g.inlinable(t.Elem()) {
+			g.p("if %s == nil {", expr)
+			g.p("w.WriteNull()")
+			g.p("} else if len(%s) == 0 {", expr)
+			g.p("w.WriteString(\"[]\")")
+			g.p("} else {")
+			g.p("w.ArrayStart()")
+			g.p("for i := range %s {", expr)
+			g.p("w.ArrayElem(i == 0)")
+			g.encodeValue(expr+"[i]", t.Elem(), false)
+			g.p("}")
+			g.p("w.ArrayEnd()")
+			g.p("}")
+			return
+		}
+	}
+
+	g.p("if err := w.Encode(&%s); err != nil {", expr)
+	g.p("return err")
+	g.p("}")
+}
+
+// inlinable reports whether encodeValue encodes values of t without
+// falling back to Writer.Encode.
+func (g *generator) inlinable(t types.Type) bool {
+	if _, ok := predeclared(t); ok {
+		return true
+	}
+	switch t := t.(type) {
+	case *types.Named:
+		return g.generated[t]
+	case *types.Pointer:
+		named, ok := t.Elem().(*types.Named)
+		return ok && g.generated[named]
+	}
+	return false
+}
+
+func (g *generator) unmarshal(named *types.Named, fields []field) error {
+	name := named.Obj().Name()
+
+	// Later fields take over the keys of earlier ones, as in the compiled
+	// decoders; each field then matches the keys it kept.
+	owner := make(map[string]int)
+	var keys []string
+	for i, f := range fields {
+		for _, key := range append([]string{f.tag.Name}, f.tag.Aliases...) {
+			if _, ok := owner[key]; !ok {
+				keys = append(keys, key)
+			}
+			owner[key] = i
+		}
+	}
+	cases := make([][]string, len(fields))
+	for _, key := range keys {
+		i := owner[key]
+		cases[i] = append(cases[i], strconv.Quote(key))
+	}
+
+	track := false
+	for _, f := range fields {
+		if f.tag.Required || f.tag.HasDefault {
+			track = true
+		}
+	}
+
+	g.p("")
+	g.p("// UnmarshalFastJSON implements fastjson.Unmarshaler.")
+	g.p("func (x *%s) UnmarshalFastJSON(it *fastjson.Iterator) error {", name)
+	g.p("var r fastjson.StructReader")
+	g.p("if err := r.Begin(it, reflect.TypeFor[%s](), %d, %t); err != nil {", name, named.Underlying().(*types.Struct).NumFields(), track)
+	g.p("return err")
+	g.p("}")
+	g.p("for {")
+	g.p("key, ok, err := r.Next()")
+	g.p("if err != nil {")
+	g.p("return err")
+	g.p("}")
+	g.p("if !ok {")
+	g.p("break")
+	g.p("}")
+	g.p("switch key {")
+	for i, f := range fields {
+		if len(cases[i]) == 0 {
+			continue
+		}
+		g.p("case %s:", strings.Join(cases[i], ", "))
+		g.p("if ok, err := r.Field(%d); err != nil {", f.index)
+		g.p("return err")
+		g.p("} else if ok {")
+		g.p("if err := r.Done(%s, %q); err != nil {", g.decodeValue(f), f.name)
+		g.p("return err")
+		g.p("}")
+		g.p("}")
+	}
+	g.p("default:")
+	g.p("if err := r.Unknown(); err != nil {")
+	g.p("return err")
+	g.p("}")
+	g.p("}")
+	g.p("}")
+
+	for _, f := range fields {
+		if f.tag.Required {
+			g.p("if !r.Seen(%d) {", f.index)
+			g.p("return r.Missing(%q, %q)", f.tag.Name, f.name)
+			g.p("}")
+		}
+	}
+	for _, f := range fields {
+		if f.tag.HasDefault {
+			if err := g.applyDefault(named, f); err != nil {
+				return err
+			}
+		}
+	}
+	g.p("return nil")
+	g.p("}")
+	return nil
+}
+
+// decodeValue returns the expression decoding the current value into f.
+func (g *generator) decodeValue(f field) string {
+	ptr := "&x." + f.name
+	if f.tag.Quoted && isQuotable(f.typ) {
+		return fmt.Sprintf("it.DecodeQuoted(%s)", ptr)
+	}
+	if b, ok := predeclared(f.typ); ok {
+		method := map[types.BasicKind]string{
+			types.String:  "DecodeString",
+			types.Int:     "DecodeInt",
+			types.Int64:   "DecodeInt64",
+			types.Int32:   "DecodeInt32",
+			types.Float64: "DecodeFloat64",
+			types.Bool:    "DecodeBool",
+		}[b.Kind()]
+		return fmt.Sprintf("it.%s(%s)", method, ptr)
+	}
+	if named, ok := f.typ.(*types.Named); ok && g.generated[named] {
+		return fmt.Sprintf("x.%s.UnmarshalFastJSON(it)", f.name)
+	}
+	return fmt.Sprintf("it.Decode(%s)", ptr)
+}
+
+// applyDefault writes the statements setting f to its default when it was
+// not present. As in the compiled decoders, defaults of quoted fields are
+// written unquoted. Every default is checked against the field's type
+// here; those of predeclared types are then turned into constants, and
+// others are decoded from their JSON on every use, through the API doing
+// the decoding, so that values never share memory.
+func (g *generator) applyDefault(named *types.Named, f field) error {
+	isString := false
+	if b, ok := f.typ.Underlying().(*types.Basic); ok {
+		isString = b.Kind() == types.String
+	}
+	raw, err := structtag.DefaultJSON(f.tag.Default, isString, isDuration(f.typ))
+	if err == nil {
+		err = g.checkDefault(raw, f.typ)
+	}
+	if err == nil {
+		var lit string
+		if lit, err = defaultLiteral(f.typ, raw); err == nil {
+			g.p("if !r.Seen(%d) {", f.index)
+			if lit != "" {
+				g.p("x.%s = %s", f.name, lit)
+			} else {
+				g.p("if err := r.Default(&x.%s, %q, %q); err != nil {", f.name, raw, f.name)
+				g.p("return err")
+				g.p("}")
+			}
+			g.p("}")
+			return nil
+		}
+	}
+	return fmt.Errorf("%s.%s: invalid default %q: %v", named.Obj().Name(), f.name, f.tag.Default, err)
+}
+
+// checkDefault parses raw and checks that the compiled decoders can store
+// it in a value of type t.
+func (g *generator) checkDefault(raw []byte, t types.Type) error {
+	p := fastjson.GetParser()
+	defer fastjson.PutParser(p)
+	v, err := p.Parse(raw)
+	if err != nil {
+		return err
+	}
+	return g.checkValue(v, t, "")
+}
+
+// checkValue reports an error if v cannot be decoded into t, which is at
+// path in the default. Named types other than the generated ones and
+// time.Duration are taken on trust: codecs may be registered for them.
+func (g *generator) checkValue(v *fastjson.Value, t types.Type, path string) error {
+	mismatch := func() error {
+		if path == "" {
+			return fmt.Errorf("cannot decode %s into %s", v.Type(), types.TypeString(t, (*types.Package).Name))
+		}
+		return fmt.Errorf("cannot decode %s into %s at %s", v.Type(), types.TypeString(t, (*types.Package).Name), path)
+	}
+
+	t = types.Unalias(t)
+	if named, ok := t.(*types.Named); ok && !g.generated[named] && !isDuration(named) {
+		return nil
+	}
+
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		switch u.Kind() {
+		case types.String:
+			if v.Type() != fastjson.TypeString {
+				return mismatch()
+			}
+		case types.Int, types.Int64, types.Int32:
+			n, err := v.Int64()
+			if err != nil || (u.Kind() == types.Int32 && n != int64(int32(n))) {
+				return mismatch()
+			}
+		case types.Float64:
+			if _, err := v.Float64(); err != nil {
+				return mismatch()
+			}
+		case types.Bool:
+			if _, err := v.Bool(); err != nil {
+				return mismatch()
+			}
+		}
+	case *types.Pointer:
+		return g.checkValue(v, u.Elem(), path)
+	case *types.Slice:
+		elems, err := v.Array()
+		if err != nil {
+			return mismatch()
+		}
+		for i, e := range elems {
+			if err := g.checkValue(e, u.Elem(), path+"["+strconv.Itoa(i)+"]"); err != nil {
+				return err
+			}
+		}
+	case *types.Map:
+		obj, err := v.Object()
+		if err != nil {
+			return mismatch()
+		}
+		obj.Visit(func(key []byte, e *fastjson.Value) {
+			if err == nil {
+				err = g.checkValue(e, u.Elem(), path+"."+string(key))
+			}
+		})
+		return err
+	case *types.Struct:
+		obj, err := v.Object()
+		if err != nil {
+			return mismatch()
+		}
+		byKey := make(map[string]field)
+		for _, f := range g.structFields(u) {
+			for _, key := range append([]string{f.tag.Name}, f.tag.Aliases...) {
+				byKey[key] = f
+			}
+		}
+		obj.Visit(func(key []byte, e *fastjson.Value) {
+			f, ok := byKey[string(key)]
+			if !ok || err != nil {
+				return
+			}
+			at := path + "." + string(key)
+			if f.tag.Quoted && isQuotable(f.typ) {
+				if e.Type() != fastjson.TypeString {
+					err = fmt.Errorf("cannot decode %s into quoted %s at %s", e.Type(), types.TypeString(f.typ, (*types.Package).Name), at)
+				}
+				return
+			}
+			err = g.checkValue(e, f.typ, at)
+		})
+		return err
+	}
+	return nil
+}
+
+// defaultLiteral returns the Go constant for raw if t is predeclared, or
+// "" if the default must be decoded at run time.
+func defaultLiteral(t types.Type, raw []byte) (string, error) {
+	b, ok := predeclared(t)
+	if !ok {
+		return "", nil
+	}
+	opts := fastjson.DecodeOptions{Strict: true}
+	switch b.Kind() {
+	case types.String:
+		var s string
+		err := fastjson.UnmarshalWithOptions(raw, &s, opts)
+		return strconv.Quote(s), err
+	case types.Int, types.Int64:
+		var n int64
+		err := fastjson.UnmarshalWithOptions(raw, &n, opts)
+		return strconv.FormatInt(n, 10), err
+	case types.Int32:
+		var n int32
+		err := fastjson.UnmarshalWithOptions(raw, &n, opts)
+		return strconv.FormatInt(int64(n), 10), err
+	case types.Float64:
+		var f float64
+		err := fastjson.UnmarshalWithOptions(raw, &f, opts)
+		return strconv.FormatFloat(f, 'g', -1, 64), err
+	default:
+		var v bool
+		err := fastjson.UnmarshalWithOptions(raw, &v, opts)
+		return strconv.FormatBool(v), err
+	}
+}
+
+// predeclared returns t as one of the predeclared types the generated code
+// handles itself. Named types are left to the compiled codecs, which may
+// have codecs registered for them.
+func predeclared(t types.Type) (*types.Basic, bool) {
+	b, ok := t.(*types.Basic)
+	if !ok {
+		return nil, false
+	}
+	switch b.Kind() {
+	case types.String, types.Int, types.Int64, types.Int32, types.Float64, types.Bool:
+		return b, true
+	}
+	return nil, false
+}
+
+// isQuotable mirrors the reflective codecs: the string option only applies
+// to the kinds they encode as numbers and bools.
+func isQuotable(t types.Type) bool {
+	b, ok := t.Underlying().(*types.Basic)
+	if !ok {
+		return false
+	}
+	switch b.Kind() {
+	case types.Int, types.Int64, types.Int32, types.Float64, types.Bool:
+		return true
+	}
+	return false
+}
+
+func isDuration(t types.Type) bool {
+	named, ok := t.(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := named.Obj()
+	return obj.Pkg() != nil && obj.Pkg().Path() == "time" && obj.Name() == "Duration"
+}
+
+// test returns the source of a test that checks the generated methods
+// against the reflective codecs on a zero and a filled-in value of each
+// type.
+func (g *generator) test() ([]byte, error) {
+	g.imports = make(map[string]string)
+	var body bytes.Buffer
+	for _, named := range g.structs {
+		name := named.Obj().Name()
+		sample, _ := g.sample(named, 0)
+		fmt.Fprintf(&body, "\tt.Run(%q, func(t *testing.T) {\n", name)
+		fmt.Fprintf(&body, "\t\tcheckFastJSONGen(t, ref, gen, &%s{})\n", name)
+		if sample != name+"{}" {
+			fmt.Fprintf(&body, "\t\tcheckFastJSONGen(t, ref, gen, &%s)\n", sample)
+		}
+		fmt.Fprintf(&body, "\t})\n")
+	}
+
+	g.buf.Reset()
+	g.buf.WriteString(header)
+	g.p("package %s", g.pkg.Name())
+	g.p("")
+	std := []string{"bytes", "reflect", "testing"}
+	other := []string{"github.com/HeartBeat1608/fastjson"}
+	for path := range g.imports {
+		if first, _, _ := strings.Cut(path, "/"); strings.Contains(first, ".") {
+			other = append(other, path)
+		} else {
+			std = append(std, path)
+		}
+	}
+	sort.Strings(std)
+	sort.Strings(other)
+
+	g.p("import (")
+	for _, path := range std {
+		g.p("%q", path)
+	}
+	g.p("")
+	for _, path := range other {
+		g.p("%q", path)
+	}
+	g.p(")")
+	g.p("")
+
+	naming := ""
+	if g.opts.namingExpr != "" {
+		naming = "NamingStrategy: " + g.opts.namingExpr
+	}
+	g.p("// TestFastJSONGenerated checks that the generated methods encode and")
+	g.p("// decode like the reflective codecs.")
+	g.p("func TestFastJSONGenerated(t *testing.T) {")
+	g.p("cfg := fastjson.Config{%s}", naming)
+	g.p("cfg.Encode.SortMapKeys = true")
+	g.p("gen := cfg.Freeze()")
+	g.p("cfg.IgnoreMarshalers = true")
+	g.p("ref := cfg.Freeze()")
+	g.p("")
+	g.buf.Write(body.Bytes())
+	g.p("}")
+	g.buf.WriteString(testHelpers)
+	return g.buf.Bytes(), nil
+}
+
+const testHelpers = `
+func checkFastJSONGen[T any](t *testing.T, ref, gen *fastjson.API, v *T) {
+	t.Helper()
+	for _, indent := range []string{"", "  "} {
+		want, wantErr := ref.MarshalIndent(v, "", indent)
+		got, gotErr := gen.MarshalIndent(v, "", indent)
+		if !sameErrorFastJSONGen(gotErr, wantErr) || !bytes.Equal(got, want) {
+			t.Fatalf("MarshalIndent(%q) = %s, %v; reflective codecs give %s, %v", indent, got, gotErr, want, wantErr)
+		}
+		if wantErr != nil {
+			continue
+		}
+
+		var fromRef, fromGen T
+		wantErr = ref.Unmarshal(want, &fromRef)
+		gotErr = gen.Unmarshal(want, &fromGen)
+		if !sameErrorFastJSONGen(gotErr, wantErr) || !reflect.DeepEqual(fromGen, fromRef) {
+			t.Fatalf("Unmarshal(%s) = %+v, %v; reflective codecs give %+v, %v", want, fromGen, gotErr, fromRef, wantErr)
+		}
+	}
+}
+
+func sameErrorFastJSONGen(a, b error) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Error() == b.Error()
+}
+
+func ptrFastJSONGen[T any](v T) *T {
+	return &v
+}
+`
+
+// maxSampleDepth bounds samples of recursive types.
+const maxSampleDepth = 3
+
+// sample returns a Go expression for a non-zero value of t, or false if it
+// should be left zero.
+func (g *generator) sample(t types.Type, depth int) (string, bool) {
+	if depth > maxSampleDepth {
+		return "", false
+	}
+
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		var lit string
+		switch u.Kind() {
+		case types.String:
+			lit = strconv.Quote("quote\" slash\\ <html> é\n")
+		case types.Int, types.Int64:
+			lit = "-42"
+		case types.Int32:
+			lit = "7"
+		case types.Float64:
+			lit = "1.5"
+		case types.Bool:
+			lit = "true"
+		default:
+			return "", false
+		}
+		if _, ok := t.(*types.Named); ok {
+			lit = g.typeString(t) + "(" + lit + ")"
+		}
+		return lit, true
+
+	case *types.Pointer:
+		elem, ok := g.sample(u.Elem(), depth+1)
+		if !ok {
+			return "", false
+		}
+		return "ptrFastJSONGen[" + g.typeString(u.Elem()) + "](" + elem + ")", true
+
+	case *types.Slice:
+		elem, ok := g.sample(u.Elem(), depth+1)
+		if !ok {
+			return "", false
+		}
+		return g.typeString(t) + "{" + elem + ", " + elem + "}", true
+
+	case *types.Map:
+		if b, ok := u.Key().Underlying().(*types.Basic); !ok || b.Kind() != types.String {
+			return "", false
+		}
+		elem, ok := g.sample(u.Elem(), depth+1)
+		if !ok {
+			return "", false
+		}
+		return g.typeString(t) + "{\"key\": " + elem + "}", true
+
+	case *types.Struct:
+		named, ok := t.(*types.Named)
+		if !ok {
+			return "", false
+		}
+		var b bytes.Buffer
+		b.WriteString(g.typeString(t) + "{")
+		for i := range u.NumFields() {
+			f := u.Field(i)
+			if !f.Exported() && named.Obj().Pkg() != g.pkg {
+				continue
+			}
+			if v, ok := g.sample(f.Type(), depth+1); ok {
+				fmt.Fprintf(&b, "%s: %s, ", f.Name(), v)
+			}
+		}
+		b.WriteString("}")
+		return b.String(), true
+	}
+	return "", false
+}
+
+func (g *generator) typeString(t types.Type) string {
+	return types.TypeString(t, func(p *types.Package) string {
+		if p == g.pkg {
+			return ""
+		}
+		g.imports[p.Path()] = p.Name()
+		return p.Name()
+	})
+}
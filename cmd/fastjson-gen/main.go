@@ -0,0 +1,219 @@
+// Command fastjson-gen writes MarshalFastJSON and UnmarshalFastJSON methods
+// for the struct types of a Go package. The methods drive fastjson's Writer
+// and Iterator directly, match field names with a switch instead of a map,
+// and honor the same struct tags as the reflective codecs, so that types
+// encode and decode exactly as before, only without reflection.
+//
+// Usage:
+//
+//	fastjson-gen [-type T1,T2] [-naming snake|camel|kebab] [-output file] [-test] [dir]
+//
+// The package in dir, "." by default, is type-checked from source. Fields
+// of predeclared string, int, int64, int32, float64 and bool types, of
+// other generated types and slices of those are encoded inline; any other
+// field goes through Writer.Encode and Iterator.Decode, which use the
+// compiled codecs, registered ones included. Because field names are fixed
+// when the methods are generated, -naming must match the NamingStrategy of
+// the APIs the types are used with.
+//
+// Defaults given with `default=` are checked when the methods are
+// generated. Those of fields left to the compiled codecs are decoded with
+// StructReader.Default, through the API doing the decoding.
+//
+// With -test, fastjson-gen also writes a test next to the output that
+// encodes and decodes sample values with and without the generated methods
+// and fails on any difference.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/HeartBeat1608/fastjson"
+)
+
+var (
+	typeNames = flag.String("type", "", "comma-separated list of struct types; all struct types if empty")
+	naming    = flag.String("naming", "", "naming strategy for fields without a tagged name: snake, camel or kebab")
+	output    = flag.String("output", "", "output file name; default <dir>/fastjson_gen.go")
+	withTest  = flag.Bool("test", false, "also write a test comparing the generated methods with the reflective codecs")
+)
+
+// namings maps -naming values to the strategy and its name in Go source.
+var namings = map[string]struct {
+	fn   fastjson.NamingStrategy
+	expr string
+}{
+	"snake": {fastjson.SnakeCase, "fastjson.SnakeCase"},
+	"camel": {fastjson.CamelCase, "fastjson.CamelCase"},
+	"kebab": {fastjson.KebabCase, "fastjson.KebabCase"},
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: fastjson-gen [flags] [dir]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	dir := "."
+	if flag.NArg() == 1 {
+		dir = flag.Arg(0)
+	}
+	if err := run(dir); err != nil {
+		fmt.Fprintf(os.Stderr, "fastjson-gen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(dir string) error {
+	opts := options{dir: dir, output: *output}
+	if opts.output == "" {
+		opts.output = filepath.Join(dir, "fastjson_gen.go")
+	}
+	if *typeNames != "" {
+		opts.types = strings.Split(*typeNames, ",")
+	}
+	if *naming != "" {
+		n, ok := namings[*naming]
+		if !ok {
+			return fmt.Errorf("unknown -naming %q", *naming)
+		}
+		opts.naming, opts.namingExpr = n.fn, n.expr
+	}
+
+	pkg, err := load(dir, opts.output)
+	if err != nil {
+		return err
+	}
+	structs, err := selectTypes(pkg, opts.types)
+	if err != nil {
+		return err
+	}
+
+	g := newGenerator(pkg, structs, opts)
+	src, err := g.methods()
+	if err != nil {
+		return err
+	}
+	if err := writeSource(opts.output, src); err != nil {
+		return err
+	}
+
+	if *withTest {
+		src, err := g.test()
+		if err != nil {
+			return err
+		}
+		return writeSource(strings.TrimSuffix(opts.output, ".go")+"_test.go", src)
+	}
+	return nil
+}
+
+type options struct {
+	dir        string
+	output     string
+	types      []string
+	naming     fastjson.NamingStrategy
+	namingExpr string
+}
+
+// load type-checks the package in dir from source, leaving out the output
+// of a previous run, whose methods may no longer match the types.
+func load(dir, output string) (*types.Package, error) {
+	bp, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	outAbs, _ := filepath.Abs(output)
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, name := range bp.GoFiles {
+		path := filepath.Join(dir, name)
+		if abs, _ := filepath.Abs(path); abs == outAbs {
+			continue
+		}
+		f, err := parser.ParseFile(fset, path, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no Go files in %s", dir)
+	}
+
+	// Errors are tolerated: code elsewhere in the package may well call
+	// the methods that are about to be generated.
+	conf := types.Config{
+		Importer: importer.ForCompiler(fset, "source", nil),
+		Error:    func(error) {},
+	}
+	pkg, _ := conf.Check(bp.ImportPath, fset, files, nil)
+	if pkg == nil {
+		return nil, fmt.Errorf("cannot type-check %s", dir)
+	}
+	return pkg, nil
+}
+
+// selectTypes returns the named struct types to generate methods for, in
+// source order of names: those listed, or every non-generic one.
+func selectTypes(pkg *types.Package, names []string) ([]*types.Named, error) {
+	var structs []*types.Named
+	if len(names) == 0 {
+		for _, name := range pkg.Scope().Names() {
+			tn, ok := pkg.Scope().Lookup(name).(*types.TypeName)
+			if !ok || tn.IsAlias() {
+				continue
+			}
+			if named, ok := tn.Type().(*types.Named); ok && isGeneratable(named) {
+				structs = append(structs, named)
+			}
+		}
+		if len(structs) == 0 {
+			return nil, fmt.Errorf("no struct types in %s", pkg.Path())
+		}
+		return structs, nil
+	}
+
+	for _, name := range names {
+		tn, ok := pkg.Scope().Lookup(strings.TrimSpace(name)).(*types.TypeName)
+		if !ok {
+			return nil, fmt.Errorf("type %s not found in %s", name, pkg.Path())
+		}
+		named, ok := tn.Type().(*types.Named)
+		if !ok || tn.IsAlias() || !isGeneratable(named) {
+			return nil, fmt.Errorf("%s is not a non-generic struct type", name)
+		}
+		structs = append(structs, named)
+	}
+	return structs, nil
+}
+
+func isGeneratable(named *types.Named) bool {
+	_, ok := named.Underlying().(*types.Struct)
+	return ok && named.TypeParams().Len() == 0
+}
+
+func writeSource(path string, src []byte) error {
+	formatted, err := format.Source(src)
+	if err != nil {
+		return fmt.Errorf("formatting %s: %v\n%s", path, err, src)
+	}
+	return os.WriteFile(path, formatted, 0o644)
+}
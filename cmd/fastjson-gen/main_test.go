@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/HeartBeat1608/fastjson"
+)
+
+// TestGoldenGentest regenerates internal/gentest and compares the result
+// with the files checked in there, which its own tests exercise.
+func TestGoldenGentest(t *testing.T) {
+	dir := filepath.Join("..", "..", "internal", "gentest")
+	opts := options{
+		dir:        dir,
+		output:     filepath.Join(dir, "types_fastjson.go"),
+		naming:     fastjson.SnakeCase,
+		namingExpr: "fastjson.SnakeCase",
+	}
+
+	pkg, err := load(dir, opts.output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	structs, err := selectTypes(pkg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := newGenerator(pkg, structs, opts)
+
+	for file, generate := range map[string]func() ([]byte, error){
+		"types_fastjson.go":      g.methods,
+		"types_fastjson_test.go": g.test,
+	} {
+		src, err := generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := format.Source(src)
+		if err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+		want, err := os.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s is out of date; run go generate in internal/gentest", file)
+		}
+	}
+}
+
+func TestSelectTypes(t *testing.T) {
+	dir := filepath.Join("..", "..", "internal", "gentest")
+	pkg, err := load(dir, filepath.Join(dir, "types_fastjson.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	structs, err := selectTypes(pkg, []string{"Order", " Line"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(structs) != 2 || structs[0].Obj().Name() != "Order" || structs[1].Obj().Name() != "Line" {
+		t.Errorf("selectTypes = %v, want Order and Line", structs)
+	}
+
+	if _, err := selectTypes(pkg, []string{"Missing"}); err == nil {
+		t.Error("selectTypes accepted an unknown type")
+	}
+}
+
+func TestInvalidDefaults(t *testing.T) {
+	tests := []struct {
+		field string
+		err   string // "" if the default is valid
+	}{
+		{`N int "fastjson:\",default=5\""`, ""},
+		{`L []int "fastjson:\",default=[1,2]\""`, ""},
+		{`T Tier "fastjson:\",default=[\\\"any\\\"]\""`, ""},
+		{`I Inner "fastjson:\",default={\\\"n\\\":\\\"2\\\"}\""`, ""},
+		{`N int "fastjson:\",default=abc\""`, "invalid default"},
+		{`N int32 "fastjson:\",default=3000000000\""`, "cannot decode number into int32"},
+		{`L []int "fastjson:\",default={}\""`, "cannot decode object into []int"},
+		{`M map[string]int "fastjson:\",default={\\\"a\\\":\\\"x\\\"}\""`, "cannot decode string into int at .a"},
+		{`I Inner "fastjson:\",default={\\\"n\\\":2}\""`, "cannot decode number into quoted int at .n"},
+		{`P *[]Inner "fastjson:\",default=[{\\\"m\\\":[true]}]\""`, "cannot decode true into int at [0].m[0]"},
+	}
+
+	for _, tt := range tests {
+		src := "package p\n" +
+			"type Tier string\n" +
+			"type Inner struct { N int `fastjson:\",format=string\"`; M []int `fastjson:\"m\"` }\n" +
+			"type T struct { " + tt.field + " }\n"
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, "p.go", src, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pkg, err := (&types.Config{}).Check("p", fset, []*ast.File{f}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		inner := pkg.Scope().Lookup("Inner").Type().(*types.Named)
+		named := pkg.Scope().Lookup("T").Type().(*types.Named)
+
+		g := newGenerator(pkg, []*types.Named{named, inner}, options{naming: fastjson.SnakeCase})
+		_, err = g.methods()
+		if tt.err == "" && err != nil {
+			t.Errorf("%s: unexpected error %v", tt.field, err)
+		}
+		if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("%s: error %v, want %q", tt.field, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package fastjson
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// Codec encodes and decodes values of type T with an encoder and decoder
+// compiled once, when the Codec is created, and called directly from then
+// on. It keeps using them even if codecs registered later would change how
+// T is handled. A Codec is safe for concurrent use.
+type Codec[T any] struct {
+	api *API
+	enc EncoderFunc
+	dec DecoderFunc
+}
+
+// NewCodec compiles a Codec for T using a's options and registrations.
+// An *UnsupportedTypeError names the first field whose type cannot be
+// handled.
+func NewCodec[T any](a *API) (*Codec[T], error) {
+	t := reflect.TypeFor[T]()
+	enc, err := a.getEncoder(t)
+	if err != nil {
+		return nil, err
+	}
+	dec, err := a.getDecoder(t)
+	if err != nil {
+		return nil, err
+	}
+	return &Codec[T]{api: a, enc: enc, dec: dec}, nil
+}
+
+// CodecFor is like NewCodec with the default options, but panics if T is
+// not supported. It is meant for package-level variables:
+//
+//	var orderCodec = fastjson.CodecFor[Order]()
+func CodecFor[T any]() *Codec[T] {
+	c, err := NewCodec[T](defaultAPI)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// Marshal returns the JSON encoding of *v, or null if v is nil.
+func (c *Codec[T]) Marshal(v *T) ([]byte, error) {
+	if v == nil {
+		return []byte("null"), nil
+	}
+
+	w := c.api.getWriter(c.api.encodeOpts)
+	defer PutWriter(w)
+	if err := encodeWith(w, c.enc, unsafe.Pointer(v)); err != nil {
+		return nil, err
+	}
+
+	result := make([]byte, len(w.Buffer))
+	copy(result, w.Buffer)
+	return result, nil
+}
+
+// Append appends the JSON encoding of *v, or null if v is nil, to dst and
+// returns the extended buffer.
+func (c *Codec[T]) Append(dst []byte, v *T) ([]byte, error) {
+	if v == nil {
+		return append(dst, "null"...), nil
+	}
+	return c.api.appendWith(dst, c.enc, unsafe.Pointer(v), c.api.encodeOpts)
+}
+
+// Unmarshal parses data and stores the result in *v. A nil v is reported
+// as an *InvalidUnmarshalError.
+func (c *Codec[T]) Unmarshal(data []byte, v *T) error {
+	if v == nil {
+		return &InvalidUnmarshalError{Type: reflect.TypeFor[*T]()}
+	}
+	return c.api.decodeWith(data, c.dec, unsafe.Pointer(v), c.api.decodeOpts)
+}
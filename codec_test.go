@@ -0,0 +1,89 @@
+package fastjson
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type OrderLine struct {
+	SKU   string         `json:"sku"`
+	Attrs map[int]string `json:"attrs"`
+}
+
+type BadOrder struct {
+	ID    int          `json:"id"`
+	Lines []*OrderLine `json:"lines"`
+}
+
+func TestCodec(t *testing.T) {
+	c := CodecFor[ComplexUser]()
+	in := ComplexUser{User: User{ID: 3, Name: "c"}, Tags: []string{"t"}, Scores: []int{1, 2}}
+
+	data, err := c.Marshal(&in)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	expected, _ := Marshal(in)
+	if string(data) != string(expected) {
+		t.Errorf("Expected %s, got %s", expected, data)
+	}
+
+	buf, err := c.Append([]byte("["), &in)
+	if err != nil || string(buf) != "["+string(expected) {
+		t.Errorf("unexpected Append result %s (err %v)", buf, err)
+	}
+
+	var out ComplexUser
+	if err := c.Unmarshal(data, &out); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("round trip mismatch: %+v", out)
+	}
+
+	// Codecs keep the options of their API.
+	strict, err := NewCodec[User](ConfigCompatibleWithStandardLibrary)
+	if err != nil {
+		t.Fatalf("NewCodec failed: %v", err)
+	}
+	var u User
+	if err := strict.Unmarshal([]byte(`{} x`), &u); err == nil {
+		t.Error("expected trailing data to be rejected")
+	}
+}
+
+func TestCodec_UnsupportedType(t *testing.T) {
+	_, err := NewCodec[BadOrder](Config{}.Freeze())
+
+	var typeErr *UnsupportedTypeError
+	if !errors.As(err, &typeErr) {
+		t.Fatalf("expected *UnsupportedTypeError, got %v", err)
+	}
+	if typeErr.Type != reflect.TypeFor[map[int]string]() || typeErr.Root != reflect.TypeFor[BadOrder]() {
+		t.Errorf("unexpected error fields: %+v", typeErr)
+	}
+	if !strings.Contains(err.Error(), "at BadOrder.Lines[].Attrs:") {
+		t.Errorf("expected field path in %q", err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected CodecFor to panic")
+		}
+	}()
+	CodecFor[BadOrder]()
+}
+
+func TestCodec_UnmarshalNil(t *testing.T) {
+	err := CodecFor[User]().Unmarshal([]byte(`{"id":1}`), nil)
+
+	var invalid *InvalidUnmarshalError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("expected *InvalidUnmarshalError, got %v", err)
+	}
+	if invalid.Type != reflect.TypeFor[*User]() {
+		t.Errorf("unexpected type %v", invalid.Type)
+	}
+}
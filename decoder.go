@@ -1,10 +1,8 @@
 package fastjson
 
 import (
-	"fmt"
+	"math"
 	"reflect"
-	"strings"
-	"sync"
 	"unsafe"
 )
 
@@ -12,8 +10,14 @@ import (
 // p is the unsafe pointer to the value we want to decode into.
 type DecoderFunc func(it *Iterator, p unsafe.Pointer) error
 
-// cache stores compiled decoders to avoid repeated reflection analysis.
-var decoderCache sync.Map
+var (
+	stringType  = reflect.TypeFor[string]()
+	intType     = reflect.TypeFor[int]()
+	int64Type   = reflect.TypeFor[int64]()
+	int32Type   = reflect.TypeFor[int32]()
+	float64Type = reflect.TypeFor[float64]()
+	boolType    = reflect.TypeFor[bool]()
+)
 
 type sliceHeader struct {
 	Data unsafe.Pointer
@@ -22,27 +26,40 @@ type sliceHeader struct {
 }
 
 // getDecoder returns a cached decoder or compiles a new one.
-func getDecoder(t reflect.Type) (DecoderFunc, error) {
-	if f, ok := decoderCache.Load(t); ok {
-		return f.(DecoderFunc), nil
+func (a *API) getDecoder(t reflect.Type) (DecoderFunc, error) {
+	gen := a.generation()
+	if f, ok := a.decoders.Load(t); ok && f.(cachedDecoder).gen == gen {
+		return f.(cachedDecoder).dec, nil
 	}
 
 	// Compile new decoder for this type.
-	dec, err := compileDecoder(t)
+	dec, err := a.compileDecoder(t)
 	if err != nil {
 		return nil, err
 	}
 
-	decoderCache.Store(t, dec)
+	a.decoders.Store(t, cachedDecoder{gen: gen, dec: dec})
 	return dec, nil
 }
 
 // compileDecoder switches on the type to return the correct primitive or struct decoder.
-func compileDecoder(t reflect.Type) (DecoderFunc, error) {
+func (a *API) compileDecoder(t reflect.Type) (DecoderFunc, error) {
+	if dec, ok := a.registeredDecoder(t); ok {
+		return dec, nil
+	}
+	if t == numberType {
+		return decodeNumber, nil
+	}
+	if !a.ignoreMarshalers && reflect.PointerTo(t).Implements(unmarshalerType) {
+		return unmarshalerDecoder(t), nil
+	}
+
 	switch t.Kind() {
 	case reflect.String:
 		return decodeString, nil
-	case reflect.Int, reflect.Int64:
+	case reflect.Int:
+		return decodeInt, nil
+	case reflect.Int64:
 		return decodeInt64, nil
 	case reflect.Int32:
 		return decodeInt32, nil
@@ -51,15 +68,21 @@ func compileDecoder(t reflect.Type) (DecoderFunc, error) {
 	case reflect.Bool:
 		return decodeBool, nil
 	case reflect.Interface:
-		return decodeInterface, nil
+		if u, ok := a.registeredUnion(t); ok {
+			return a.compileUnionDecoder(t, u)
+		}
+		if t.NumMethod() > 0 {
+			return a.methodInterfaceDecoder(t), nil
+		}
+		return a.decodeInterface, nil
 	case reflect.Struct:
-		return compileStructDecoder(t)
+		return a.compileStructDecoder(t, "")
 	case reflect.Slice:
-		return compileSliceDecoder(t)
+		return a.compileSliceDecoder(t)
 	case reflect.Map:
-		return compileMapDecoder(t)
+		return a.compileMapDecoder(t)
 	case reflect.Pointer:
-		elemDec, err := compileDecoder(t.Elem())
+		elemDec, err := a.compileDecoder(t.Elem())
 		if err != nil {
 			return nil, err
 		}
@@ -73,12 +96,19 @@ func compileDecoder(t reflect.Type) (DecoderFunc, error) {
 			return elemDec(it, ptrVal)
 		}, nil
 	default:
-		return nil, fmt.Errorf("fastjson: unsupported type: %s", t.Kind())
+		return nil, &UnsupportedTypeError{Type: t, Reason: t.Kind().String() + " values are not supported"}
 	}
 }
 
 // Primitive decoders
+//
+// Each checks the kind of the upcoming value first, so that a mismatch is
+// reported as an *UnmarshalTypeError with the value left unconsumed.
 func decodeString(it *Iterator, p unsafe.Pointer) error {
+	it.skipWhiteSpace()
+	if it.char() != '"' {
+		return it.typeError(stringType)
+	}
 	s, err := it.ReadString()
 	if err != nil {
 		return err
@@ -87,8 +117,8 @@ func decodeString(it *Iterator, p unsafe.Pointer) error {
 	return nil
 }
 
-func decodeInt64(it *Iterator, p unsafe.Pointer) error {
-	i, err := it.ReadInt64()
+func decodeInt(it *Iterator, p unsafe.Pointer) error {
+	i, err := it.readInt(intType, math.MinInt, math.MaxInt)
 	if err != nil {
 		return err
 	}
@@ -96,8 +126,17 @@ func decodeInt64(it *Iterator, p unsafe.Pointer) error {
 	return nil
 }
 
+func decodeInt64(it *Iterator, p unsafe.Pointer) error {
+	i, err := it.readInt(int64Type, math.MinInt64, math.MaxInt64)
+	if err != nil {
+		return err
+	}
+	*(*int64)(p) = i
+	return nil
+}
+
 func decodeInt32(it *Iterator, p unsafe.Pointer) error {
-	i, err := it.ReadInt64()
+	i, err := it.readInt(int32Type, math.MinInt32, math.MaxInt32)
 	if err != nil {
 		return err
 	}
@@ -106,6 +145,10 @@ func decodeInt32(it *Iterator, p unsafe.Pointer) error {
 }
 
 func decodeFloat64(it *Iterator, p unsafe.Pointer) error {
+	it.skipWhiteSpace()
+	if !isNumberStart(it.char()) {
+		return it.typeError(float64Type)
+	}
 	f, err := it.ReadFloat64()
 	if err != nil {
 		return err
@@ -115,6 +158,10 @@ func decodeFloat64(it *Iterator, p unsafe.Pointer) error {
 }
 
 func decodeBool(it *Iterator, p unsafe.Pointer) error {
+	it.skipWhiteSpace()
+	if c := it.char(); c != 't' && c != 'f' {
+		return it.typeError(boolType)
+	}
 	b, err := it.ReadBool()
 	if err != nil {
 		return err
@@ -123,15 +170,59 @@ func decodeBool(it *Iterator, p unsafe.Pointer) error {
 	return nil
 }
 
+// The Decode methods expose the primitive decoders to hand-written and
+// generated UnmarshalFastJSON methods, so that these report errors exactly
+// as compiled decoders do.
+
+// DecodeString decodes a string into *p. A value of another kind is
+// reported as an *UnmarshalTypeError and left unconsumed.
+func (it *Iterator) DecodeString(p *string) error { return decodeString(it, unsafe.Pointer(p)) }
+
+// DecodeInt decodes an integer into *p, rejecting fractions and values out
+// of range as type errors.
+func (it *Iterator) DecodeInt(p *int) error { return decodeInt(it, unsafe.Pointer(p)) }
+
+// DecodeInt64 is like DecodeInt for int64.
+func (it *Iterator) DecodeInt64(p *int64) error { return decodeInt64(it, unsafe.Pointer(p)) }
+
+// DecodeInt32 is like DecodeInt for int32.
+func (it *Iterator) DecodeInt32(p *int32) error { return decodeInt32(it, unsafe.Pointer(p)) }
+
+// DecodeFloat64 decodes a number into *p.
+func (it *Iterator) DecodeFloat64(p *float64) error { return decodeFloat64(it, unsafe.Pointer(p)) }
+
+// DecodeBool decodes true or false into *p.
+func (it *Iterator) DecodeBool(p *bool) error { return decodeBool(it, unsafe.Pointer(p)) }
+
+// readInt reads an integer for a Go value of type t, reporting fractional or
+// out of range numbers as type errors rather than syntax errors.
+func (it *Iterator) readInt(t reflect.Type, lo, hi int64) (int64, error) {
+	it.skipWhiteSpace()
+	start := it.head
+	if !isNumberStart(it.char()) {
+		return 0, it.typeError(t)
+	}
+
+	i, frac, overflow, err := it.scanInt()
+	if err != nil {
+		return 0, err
+	}
+	if frac || overflow || i < lo || i > hi {
+		it.head = start
+		return 0, it.typeError(t)
+	}
+	return i, nil
+}
+
 // Complex decoders
 
 // compileStructDecoder handles []T
-func compileSliceDecoder(t reflect.Type) (DecoderFunc, error) {
+func (a *API) compileSliceDecoder(t reflect.Type) (DecoderFunc, error) {
 	elemType := t.Elem()
 	elemSize := elemType.Size()
-	elemDec, err := compileDecoder(elemType)
+	elemDec, err := a.compileDecoder(elemType)
 	if err != nil {
-		return nil, err
+		return nil, withTypePath(err, t, "[]")
 	}
 
 	// sliceType := t
@@ -139,6 +230,10 @@ func compileSliceDecoder(t reflect.Type) (DecoderFunc, error) {
 	return func(it *Iterator, p unsafe.Pointer) error {
 		header := (*sliceHeader)(p)
 
+		it.skipWhiteSpace()
+		if it.char() != '[' {
+			return it.typeError(t)
+		}
 		if err := it.ReadArrayStart(); err != nil {
 			return err
 		}
@@ -149,9 +244,14 @@ func compileSliceDecoder(t reflect.Type) (DecoderFunc, error) {
 			it.skipWhiteSpace()
 			if it.head < it.dataLen && it.data[it.head] == ']' {
 				it.head++
+				it.depth--
 				return nil
 			}
 
+			if max := it.opts.MaxArrayElements; max > 0 && header.Len >= max {
+				return it.limitError("MaxArrayElements", max)
+			}
+
 			if header.Len >= header.Cap {
 				newCap := header.Cap * 2
 				if newCap == 0 {
@@ -168,41 +268,55 @@ func compileSliceDecoder(t reflect.Type) (DecoderFunc, error) {
 
 			elemPtr := unsafe.Pointer(uintptr(header.Data) + uintptr(header.Len)*elemSize)
 
+			mark := len(it.errs)
 			if err := elemDec(it, elemPtr); err != nil {
-				return err
+				if err := it.collect(err); err != nil {
+					return withIndexPath(err, header.Len)
+				}
+			}
+			for _, e := range it.errs[mark:] {
+				withIndexPath(e, header.Len)
 			}
 			header.Len++
 
 			it.skipWhiteSpace()
 			if it.head < it.dataLen && it.data[it.head] == ',' {
 				it.head++
+				if err := it.rejectTrailingComma(']'); err != nil {
+					return err
+				}
 				continue
 			} else if it.head < it.dataLen && it.data[it.head] == ']' {
 				it.head++
+				it.depth--
 				return nil
 			} else {
-				return it.error("expected ',' or ']'")
+				return it.expected("',' or ']'")
 			}
 		}
 	}, nil
 }
 
 // compileStructDecoder handles map[string]T
-func compileMapDecoder(t reflect.Type) (DecoderFunc, error) {
+func (a *API) compileMapDecoder(t reflect.Type) (DecoderFunc, error) {
 	keyType := t.Key()
 	if keyType.Kind() != reflect.String {
-		return nil, fmt.Errorf("fastjson: maps with %s keys not supported", keyType.Kind())
+		return nil, &UnsupportedTypeError{Type: t, Reason: "map keys must be strings"}
 	}
 
 	elemType := t.Elem()
-	elemDec, err := compileDecoder(elemType)
+	elemDec, err := a.compileDecoder(elemType)
 	if err != nil {
-		return nil, err
+		return nil, withTypePath(err, t, "[]")
 	}
 
 	mapType := t
 
 	return func(it *Iterator, p unsafe.Pointer) error {
+		it.skipWhiteSpace()
+		if it.char() != '{' {
+			return it.typeError(mapType)
+		}
 		if err := it.ReadObjectStart(); err != nil {
 			return err
 		}
@@ -213,13 +327,28 @@ func compileMapDecoder(t reflect.Type) (DecoderFunc, error) {
 			mapVal.Set(reflect.MakeMap(mapType))
 		}
 
+		// The map may already hold entries from the caller, so duplicates are
+		// tracked against the input rather than the map itself.
+		var seen map[string]int
+		if it.opts.DuplicateKeys != DuplicateKeyLastWins {
+			seen = make(map[string]int)
+		}
+
+		keys := 0
 		for {
 			it.skipWhiteSpace()
 			if it.head < it.dataLen && it.data[it.head] == '}' {
 				it.head++
+				it.depth--
 				return nil
 			}
 
+			if max := it.opts.MaxObjectKeys; max > 0 && keys >= max {
+				return it.limitError("MaxObjectKeys", max)
+			}
+			keys++
+
+			keyStart := it.head
 			key, err := it.ReadString()
 			if err != nil {
 				return err
@@ -229,75 +358,194 @@ func compileMapDecoder(t reflect.Type) (DecoderFunc, error) {
 				return err
 			}
 
-			// Create a new value for the element
-			// We allocate a new one because maps store pointers/copies internally
-			newElem := reflect.New(elemType) // returns *T
-
-			if err := elemDec(it, unsafe.Pointer(newElem.Pointer())); err != nil {
-				return err
+			dup := false
+			if seen != nil {
+				if dup, err = it.checkDuplicate(seen, key, keyStart); err != nil {
+					return err
+				}
 			}
 
-			mapVal.SetMapIndex(reflect.ValueOf(key), newElem.Elem())
+			if dup {
+				if err := it.SkipValue(); err != nil {
+					return err
+				}
+			} else {
+				// Create a new value for the element
+				// We allocate a new one because maps store pointers/copies internally
+				newElem := reflect.New(elemType) // returns *T
+
+				mark := len(it.errs)
+				if err := elemDec(it, unsafe.Pointer(newElem.Pointer())); err != nil {
+					if err := it.collect(err); err != nil {
+						return withKeyPath(err, key)
+					}
+				} else {
+					mapVal.SetMapIndex(reflect.ValueOf(key), newElem.Elem())
+				}
+				for _, e := range it.errs[mark:] {
+					withKeyPath(e, key)
+				}
+			}
 			it.skipWhiteSpace()
 			if it.head < it.dataLen && it.data[it.head] == ',' {
 				it.head++
+				if err := it.rejectTrailingComma('}'); err != nil {
+					return err
+				}
 				continue
 			} else if it.head < it.dataLen && it.data[it.head] == '}' {
 				it.head++
+				it.depth--
 				return nil
 			} else {
-				return it.error("expected ',' or '}'")
+				return it.expected("',' or '}'")
 			}
 		}
 	}, nil
 }
 
 type fieldInfo struct {
+	name    string // Go field name, for error reporting
+	key     string // primary JSON key
+	index   int
 	offset  uintptr
 	decoder DecoderFunc
+	def     *fieldDefault
+}
+
+// quotedDecoder decodes a value written inside a JSON string with dec.
+// Anything but a string holding exactly one such value is a type error.
+func quotedDecoder(t reflect.Type, dec DecoderFunc) DecoderFunc {
+	return func(it *Iterator, p unsafe.Pointer) error {
+		it.skipWhiteSpace()
+		start := it.head
+		if it.char() != '"' {
+			return it.typeError(t)
+		}
+		s, err := it.ReadString()
+		if err != nil {
+			return err
+		}
+		end := it.head
+
+		if len(s) > 0 && unsafe.StringData(s) != &it.data[start+1] {
+			// The string was unescaped into a copy, so decode that.
+			inner := NewIterator([]byte(s))
+			inner.SetOptions(it.opts)
+			if err := dec(inner, p); err != nil || !inner.atEnd() {
+				it.head = start
+				return it.typeError(t)
+			}
+			return nil
+		}
+
+		// Decode the contents where they are, with the input cut short at
+		// the closing quote. A failure leaves no trace of the attempt.
+		dataLen, depth, mark := it.dataLen, it.depth, len(it.errs)
+		it.head, it.dataLen = start+1, end-1
+		err = dec(it, p)
+		ok := err == nil && it.atEnd()
+		it.dataLen = dataLen
+		if !ok {
+			it.head, it.depth, it.errs = start, depth, it.errs[:mark]
+			return it.typeError(t)
+		}
+		it.head = end
+		return nil
+	}
 }
 
-func compileStructDecoder(t reflect.Type) (DecoderFunc, error) {
+// compileStructDecoder compiles a decoder for struct type t. discriminator,
+// when not empty, names a union's discriminator key, which is accepted
+// without a matching field even under DisallowUnknownFields.
+func (a *API) compileStructDecoder(t reflect.Type, discriminator string) (DecoderFunc, error) {
 	fieldMap := make(map[string]*fieldInfo)
+	var defaults, required []*fieldInfo
 
 	for i := 0; i < t.NumField(); i++ {
 		field := t.Field(i)
-		tag := field.Tag.Get("json")
-		if tag == "-" {
+		tag := parseTag(field, a.naming)
+		if tag.Skip {
 			continue
 		}
 
-		name := field.Name
-		if tag != "" {
-			parts := strings.Split(tag, ",")
-			if parts[0] != "" {
-				name = parts[0]
-			}
-		}
-
-		dec, err := compileDecoder(field.Type)
+		dec, err := a.compileDecoder(field.Type)
 		if err != nil {
-			return nil, err
+			return nil, withTypePath(err, t, "."+field.Name)
 		}
 
-		fieldMap[name] = &fieldInfo{
+		info := &fieldInfo{
+			name:    field.Name,
+			key:     tag.Name,
+			index:   i,
 			offset:  field.Offset,
 			decoder: dec,
 		}
+
+		// Defaults are written as plain values even for quoted fields;
+		// the quoting only applies to the input.
+		if tag.HasDefault {
+			info.def, err = a.compileDefault(field, dec, tag.Default)
+			if err != nil {
+				return nil, err
+			}
+			defaults = append(defaults, info)
+		}
+		if tag.Quoted && isQuotable(field.Type) {
+			info.decoder = quotedDecoder(field.Type, dec)
+		}
+		if tag.Required {
+			required = append(required, info)
+		}
+
+		fieldMap[tag.Name] = info
+		for _, alias := range tag.Aliases {
+			fieldMap[alias] = info
+		}
 	}
 
+	numFields := t.NumField()
+
 	return func(it *Iterator, p unsafe.Pointer) error {
+		it.skipWhiteSpace()
+		if it.char() != '{' {
+			return it.typeError(t)
+		}
 		if err := it.ReadObjectStart(); err != nil {
 			return err
 		}
 
+		// Presence is only tracked when something needs it, so structs
+		// without defaults or required fields keep the plain loop below
+		// under the default policy.
+		policy := it.opts.DuplicateKeys
+		var seenBuf [2]uint64
+		var seen fieldSet
+		var keyAtBuf [16]int
+		var keyAt []int
+		var unknown map[string]int // offsets of unknown keys, under DuplicateKeyReject
+		if len(defaults) > 0 || len(required) > 0 || policy != DuplicateKeyLastWins {
+			seen = newFieldSet(seenBuf[:0], numFields)
+			if policy == DuplicateKeyReject {
+				keyAt = keyOffsets(keyAtBuf[:0], numFields)
+			}
+		}
+
+		keys := 0
 		for {
 			it.skipWhiteSpace()
 			if it.head < it.dataLen && it.data[it.head] == '}' {
 				it.head++
-				return nil
+				it.depth--
+				break
+			}
+
+			if max := it.opts.MaxObjectKeys; max > 0 && keys >= max {
+				return it.limitError("MaxObjectKeys", max)
 			}
+			keys++
 
+			keyStart := it.head
 			key, err := it.ReadString()
 			if err != nil {
 				return err
@@ -309,12 +557,45 @@ func compileStructDecoder(t reflect.Type) (DecoderFunc, error) {
 
 			it.skipWhiteSpace()
 
-			if info, ok := fieldMap[key]; ok {
+			info, ok := fieldMap[key]
+			if ok && seen != nil {
+				if !seen.has(info.index) {
+					seen.add(info.index)
+					if keyAt != nil {
+						keyAt[info.index] = keyStart
+					}
+				} else if policy == DuplicateKeyReject {
+					return &DuplicateKeyError{Key: key, First: keyAt[info.index], Offset: keyStart}
+				} else if policy == DuplicateKeyFirstWins {
+					ok = false
+				}
+			}
+
+			if ok {
 				fieldPtr := unsafe.Pointer(uintptr(p) + info.offset)
+				mark := len(it.errs)
 				if err := info.decoder(it, fieldPtr); err != nil {
-					return err
+					if err := it.collect(err); err != nil {
+						return withFieldPath(err, t, info.name, key)
+					}
+				}
+				for _, e := range it.errs[mark:] {
+					withFieldPath(e, t, info.name, key)
 				}
 			} else {
+				if _, known := fieldMap[key]; !known {
+					if it.opts.DisallowUnknownFields && key != discriminator {
+						return &UnknownFieldError{Key: key, Struct: t.Name(), Offset: keyStart}
+					}
+					if policy == DuplicateKeyReject {
+						if unknown == nil {
+							unknown = make(map[string]int)
+						}
+						if _, err := it.checkDuplicate(unknown, key, keyStart); err != nil {
+							return err
+						}
+					}
+				}
 				if err := it.SkipValue(); err != nil {
 					return err
 				}
@@ -323,18 +604,70 @@ func compileStructDecoder(t reflect.Type) (DecoderFunc, error) {
 			it.skipWhiteSpace()
 			if it.head < it.dataLen && it.data[it.head] == ',' {
 				it.head++
+				if err := it.rejectTrailingComma('}'); err != nil {
+					return err
+				}
 				continue
 			} else if it.head < it.dataLen && it.data[it.head] == '}' {
 				it.head++
-				return nil
+				it.depth--
+				break
 			} else {
-				return it.error("expected ',' or '}'")
+				return it.expected("',' or '}'")
+			}
+		}
+
+		for _, info := range required {
+			if !seen.has(info.index) {
+				return &RequiredFieldError{Key: info.key, Struct: t.Name(), Field: info.name, Offset: it.head}
 			}
 		}
+		for _, info := range defaults {
+			if !seen.has(info.index) {
+				info.def.apply(unsafe.Pointer(uintptr(p) + info.offset))
+			}
+		}
+		return nil
 	}, nil
 }
 
-func decodeInterface(it *Iterator, p unsafe.Pointer) error {
+// methodInterfaceDecoder decodes into an interface with methods. Without a
+// registered union it can only decode through a pointer the interface
+// already holds, as decodeInterface does, or set it to nil.
+func (a *API) methodInterfaceDecoder(t reflect.Type) DecoderFunc {
+	return func(it *Iterator, p unsafe.Pointer) error {
+		iv := reflect.NewAt(t, p).Elem()
+		it.skipWhiteSpace()
+		if it.char() != 'n' {
+			if !iv.IsNil() {
+				if ok, err := a.decodeHeld(it, iv.Elem()); ok {
+					return err
+				}
+			}
+			return it.typeError(t)
+		}
+		if err := it.ReadNull(); err != nil {
+			return err
+		}
+		iv.SetZero()
+		return nil
+	}
+}
+
+// decodeInterface decodes into an empty interface. Like encoding/json, it
+// decodes through a non-nil pointer the interface already holds, so that
+// callers can choose the concrete type; otherwise it stores the generic
+// form of the value, as readValue returns it. null always stores nil.
+func (a *API) decodeInterface(it *Iterator, p unsafe.Pointer) error {
+	if held := *(*any)(p); held != nil {
+		it.skipWhiteSpace()
+		if it.char() != 'n' {
+			if ok, err := a.decodeHeld(it, reflect.ValueOf(held)); ok {
+				return err
+			}
+		}
+	}
+
 	val, err := readValue(it)
 	if err != nil {
 		return err
@@ -343,10 +676,23 @@ func decodeInterface(it *Iterator, p unsafe.Pointer) error {
 	return nil
 }
 
+// decodeHeld decodes into held, the dynamic value of an interface, if it is
+// a non-nil pointer. It reports whether it did.
+func (a *API) decodeHeld(it *Iterator, held reflect.Value) (bool, error) {
+	if held.Kind() != reflect.Pointer || held.IsNil() {
+		return false, nil
+	}
+	dec, err := a.getDecoder(held.Type().Elem())
+	if err != nil {
+		return true, err
+	}
+	return true, dec(it, held.UnsafePointer())
+}
+
 func readValue(it *Iterator) (any, error) {
 	it.skipWhiteSpace()
 	if it.head >= it.dataLen {
-		return nil, it.error("unexpected end of JSON")
+		return nil, it.expected("value")
 	}
 
 	c := it.data[it.head]
@@ -362,9 +708,12 @@ func readValue(it *Iterator) (any, error) {
 	case 'n':
 		return nil, it.ReadNull()
 	case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		if it.opts.UseNumber {
+			return it.readNumber()
+		}
 		return it.ReadFloat64()
 	default:
-		return nil, it.error(fmt.Sprintf("unexpected character: %c", c))
+		return nil, it.expected("value")
 	}
 }
 
@@ -377,10 +726,22 @@ func readGenericMap(it *Iterator) (any, error) {
 	it.skipWhiteSpace()
 	if it.head < it.dataLen && it.data[it.head] == '}' {
 		it.head++
+		it.depth--
 		return m, nil
 	}
 
-	for {
+	var seen map[string]int
+	if it.opts.DuplicateKeys != DuplicateKeyLastWins {
+		seen = make(map[string]int)
+	}
+
+	for keys := 0; ; keys++ {
+		if max := it.opts.MaxObjectKeys; max > 0 && keys >= max {
+			return nil, it.limitError("MaxObjectKeys", max)
+		}
+
+		it.skipWhiteSpace()
+		keyStart := it.head
 		key, err := it.ReadString()
 		if err != nil {
 			return nil, err
@@ -390,12 +751,25 @@ func readGenericMap(it *Iterator) (any, error) {
 			return nil, err
 		}
 
-		val, err := readValue(it)
-		if err != nil {
-			return nil, err
+		dup := false
+		if seen != nil {
+			if dup, err = it.checkDuplicate(seen, key, keyStart); err != nil {
+				return nil, err
+			}
 		}
 
-		m[key] = val
+		if dup {
+			if err := it.SkipValue(); err != nil {
+				return nil, err
+			}
+		} else {
+			val, err := readValue(it)
+			if err != nil {
+				return nil, err
+			}
+
+			m[key] = val
+		}
 
 		it.skipWhiteSpace()
 		if it.head < it.dataLen && it.data[it.head] == ',' {
@@ -403,9 +777,10 @@ func readGenericMap(it *Iterator) (any, error) {
 			continue
 		} else if it.head < it.dataLen && it.data[it.head] == '}' {
 			it.head++
+			it.depth--
 			return m, nil
 		} else {
-			return nil, it.error("expected ',' or '}'")
+			return nil, it.expected("',' or '}'")
 		}
 	}
 }
@@ -419,10 +794,15 @@ func readGenericSlice(it *Iterator) (any, error) {
 	it.skipWhiteSpace()
 	if it.head < it.dataLen && it.data[it.head] == ']' {
 		it.head++
+		it.depth--
 		return l, nil
 	}
 
 	for {
+		if max := it.opts.MaxArrayElements; max > 0 && len(l) >= max {
+			return nil, it.limitError("MaxArrayElements", max)
+		}
+
 		val, err := readValue(it)
 		if err != nil {
 			return nil, err
@@ -436,9 +816,24 @@ func readGenericSlice(it *Iterator) (any, error) {
 			continue
 		} else if it.head < it.dataLen && it.data[it.head] == ']' {
 			it.head++
+			it.depth--
 			return l, nil
 		} else {
-			return nil, it.error("expected ',' or ']'")
+			return nil, it.expected("',' or ']'")
 		}
 	}
 }
+
+// checkDuplicate records key in seen and reports whether its value should be
+// skipped because an earlier occurrence wins.
+func (it *Iterator) checkDuplicate(seen map[string]int, key string, offset int) (bool, error) {
+	first, ok := seen[key]
+	if !ok {
+		seen[key] = offset
+		return false, nil
+	}
+	if it.opts.DuplicateKeys == DuplicateKeyReject {
+		return false, &DuplicateKeyError{Key: key, First: first, Offset: offset}
+	}
+	return true, nil
+}
@@ -0,0 +1,195 @@
+package fastjson
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+	"unsafe"
+
+	"github.com/HeartBeat1608/fastjson/internal/structtag"
+)
+
+var durationType = reflect.TypeFor[time.Duration]()
+
+// fieldDefault is a `default=` tag value, decoded once when the struct
+// decoder is compiled.
+type fieldDefault struct {
+	typ   reflect.Type
+	value reflect.Value
+
+	// shared reports whether value can be copied into every decoded struct
+	// as is. Types holding slices, maps or pointers get a deep copy
+	// instead, so that callers never alias (and mutate) the default itself.
+	shared bool
+}
+
+func (a *API) compileDefault(field reflect.StructField, dec DecoderFunc, def string) (*fieldDefault, error) {
+	raw, err := structtag.DefaultJSON(def, field.Type.Kind() == reflect.String, field.Type == durationType)
+	if err != nil {
+		return nil, fmt.Errorf("fastjson: invalid default for field %s: %w", field.Name, err)
+	}
+
+	val := reflect.New(field.Type)
+	if err := a.decodeDefault(raw, dec, val.UnsafePointer()); err != nil {
+		return nil, fmt.Errorf("fastjson: invalid default %q for field %s: %w", def, field.Name, err)
+	}
+
+	return &fieldDefault{
+		typ:    field.Type,
+		value:  val.Elem(),
+		shared: isPlainType(field.Type),
+	}, nil
+}
+
+// decodeDefault decodes raw, the JSON of a default, into the value at p
+// with dec, which a compiled. Anything after the value is an error.
+func (a *API) decodeDefault(raw []byte, dec DecoderFunc, p unsafe.Pointer) error {
+	it := NewIterator(raw)
+	it.api = a
+	err := dec(it, p)
+	if err == nil && !it.atEnd() {
+		err = it.error("unexpected data after default value")
+	}
+	return err
+}
+
+func (d *fieldDefault) apply(p unsafe.Pointer) {
+	dst := reflect.NewAt(d.typ, p).Elem()
+	if d.shared {
+		dst.Set(d.value)
+		return
+	}
+	deepCopy(dst, d.value)
+}
+
+// deepCopy sets dst, which must be settable, to a copy of src that shares
+// no slices, maps or pointers with it.
+func deepCopy(dst, src reflect.Value) {
+	t := src.Type()
+	if isPlainType(t) {
+		dst.Set(src)
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		if src.IsNil() {
+			dst.SetZero()
+			return
+		}
+		p := reflect.New(t.Elem())
+		deepCopy(p.Elem(), src.Elem())
+		dst.Set(p)
+	case reflect.Slice:
+		if src.IsNil() {
+			dst.SetZero()
+			return
+		}
+		s := reflect.MakeSlice(t, src.Len(), src.Len())
+		if isPlainType(t.Elem()) {
+			reflect.Copy(s, src)
+		} else {
+			for i := range src.Len() {
+				deepCopy(s.Index(i), src.Index(i))
+			}
+		}
+		dst.Set(s)
+	case reflect.Array:
+		for i := range src.Len() {
+			deepCopy(dst.Index(i), src.Index(i))
+		}
+	case reflect.Map:
+		if src.IsNil() {
+			dst.SetZero()
+			return
+		}
+		m := reflect.MakeMapWithSize(t, src.Len())
+		iter := src.MapRange()
+		for iter.Next() {
+			v := reflect.New(t.Elem()).Elem()
+			deepCopy(v, iter.Value())
+			m.SetMapIndex(iter.Key(), v)
+		}
+		dst.Set(m)
+	case reflect.Struct:
+		// Go through unsafe so that unexported fields, which registered
+		// decoders and UnmarshalFastJSON methods may set, are copied too.
+		if !src.CanAddr() {
+			tmp := reflect.New(t).Elem()
+			tmp.Set(src)
+			src = tmp
+		}
+		for i := range t.NumField() {
+			ft := t.Field(i).Type
+			deepCopy(reflect.NewAt(ft, unsafe.Pointer(dst.Field(i).UnsafeAddr())).Elem(),
+				reflect.NewAt(ft, unsafe.Pointer(src.Field(i).UnsafeAddr())).Elem())
+		}
+	case reflect.Interface:
+		if src.IsNil() {
+			dst.SetZero()
+			return
+		}
+		v := reflect.New(src.Elem().Type()).Elem()
+		deepCopy(v, src.Elem())
+		dst.Set(v)
+	default:
+		// Channels and funcs are references by nature.
+		dst.Set(src)
+	}
+}
+
+// isPlainType reports whether values of t can be copied without sharing
+// mutable memory.
+func isPlainType(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return true
+	case reflect.Array:
+		return isPlainType(t.Elem())
+	case reflect.Struct:
+		for i := range t.NumField() {
+			if !isPlainType(t.Field(i).Type) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+// fieldSet is a bitset of struct field indexes.
+type fieldSet []uint64
+
+// newFieldSet returns an empty set able to hold n fields, reusing buf when it
+// is large enough so that small structs need no allocation.
+func newFieldSet(buf []uint64, n int) fieldSet {
+	words := (n + 63) / 64
+	if words <= cap(buf) {
+		s := buf[:words]
+		clear(s)
+		return s
+	}
+	return make(fieldSet, words)
+}
+
+func (s fieldSet) add(i int) {
+	s[i/64] |= 1 << (i % 64)
+}
+
+func (s fieldSet) has(i int) bool {
+	return s[i/64]&(1<<(i%64)) != 0
+}
+
+// keyOffsets returns a slice of n key offsets, using buf when it is large
+// enough. The offsets are written before they are read, so they are not
+// cleared.
+func keyOffsets(buf []int, n int) []int {
+	if n <= cap(buf) {
+		return buf[:n]
+	}
+	return make([]int, n)
+}
@@ -0,0 +1,113 @@
+package fastjson
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type ServerConfig struct {
+	Host    string        `json:"host,default=localhost"`
+	Port    int           `json:"port,default=8080"`
+	Timeout time.Duration `json:"timeout,default=30s"`
+	Debug   bool          `json:"debug,default=true"`
+	Tags    []string      `json:"tags,default=[\"a\",\"b\"]"`
+	Name    string        `json:"name"`
+}
+
+func TestUnmarshal_Defaults(t *testing.T) {
+	var c ServerConfig
+	if err := Unmarshal([]byte(`{"port": 9090, "debug": false}`), &c); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	if c.Host != "localhost" {
+		t.Errorf("expected default host, got %q", c.Host)
+	}
+	if c.Port != 9090 {
+		t.Errorf("expected explicit port to win, got %d", c.Port)
+	}
+	if c.Timeout != 30*time.Second {
+		t.Errorf("expected 30s timeout, got %v", c.Timeout)
+	}
+	if c.Debug {
+		t.Errorf("expected explicit false to win over default")
+	}
+	if len(c.Tags) != 2 || c.Tags[0] != "a" || c.Tags[1] != "b" {
+		t.Errorf("unexpected default tags: %v", c.Tags)
+	}
+}
+
+func TestUnmarshal_DefaultsNotShared(t *testing.T) {
+	var a, b ServerConfig
+	if err := Unmarshal([]byte(`{}`), &a); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	a.Tags[0] = "mutated"
+
+	if err := Unmarshal([]byte(`{}`), &b); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if b.Tags[0] != "a" {
+		t.Errorf("default slice was shared between decodes: %v", b.Tags)
+	}
+}
+
+type nestedDefaults struct {
+	Limits map[string][]int `json:"limits,default={\"a\":[1,2]}"`
+	Owner  *User            `json:"owner,default={\"id\":7}"`
+	Extra  any              `json:"extra,default={\"k\":[true]}"`
+}
+
+func TestUnmarshal_NestedDefaultsNotShared(t *testing.T) {
+	var a, b nestedDefaults
+	if err := Unmarshal([]byte(`{}`), &a); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	a.Limits["a"][0] = 99
+	a.Limits["b"] = nil
+	a.Owner.ID = 99
+	a.Extra.(map[string]any)["k"].([]any)[0] = false
+
+	if err := Unmarshal([]byte(`{}`), &b); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	want := nestedDefaults{
+		Limits: map[string][]int{"a": {1, 2}},
+		Owner:  &User{ID: 7},
+		Extra:  map[string]any{"k": []any{true}},
+	}
+	if !reflect.DeepEqual(b, want) {
+		t.Errorf("defaults were shared between decodes: %+v", b)
+	}
+}
+
+func TestUnmarshal_InvalidDefault(t *testing.T) {
+	var v struct {
+		N int `json:"n,default=abc"`
+	}
+	if err := Unmarshal([]byte(`{}`), &v); err == nil {
+		t.Errorf("expected compile error for invalid default")
+	}
+}
+
+func TestUnmarshal_QuotedFieldDefault(t *testing.T) {
+	var v struct {
+		N int     `fastjson:"n,string,default=5"`
+		F float64 `fastjson:"f,string,default=1.5"`
+	}
+	if err := Unmarshal([]byte(`{}`), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.N != 5 || v.F != 1.5 {
+		t.Errorf("unexpected defaults: %+v", v)
+	}
+
+	// The input is still read quoted.
+	if err := Unmarshal([]byte(`{"n":"7","f":"2.5"}`), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.N != 7 || v.F != 2.5 {
+		t.Errorf("unexpected values: %+v", v)
+	}
+}
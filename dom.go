@@ -0,0 +1,527 @@
+package fastjson
+
+import (
+	"bytes"
+	"strconv"
+	"sync"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Type is the type of a JSON value.
+type Type uint8
+
+const (
+	TypeNull Type = iota
+	TypeObject
+	TypeArray
+	TypeString
+	TypeNumber
+	TypeTrue
+	TypeFalse
+)
+
+func (t Type) String() string {
+	switch t {
+	case TypeNull:
+		return "null"
+	case TypeObject:
+		return "object"
+	case TypeArray:
+		return "array"
+	case TypeString:
+		return "string"
+	case TypeNumber:
+		return "number"
+	case TypeTrue:
+		return "true"
+	case TypeFalse:
+		return "false"
+	}
+	return "Type(" + strconv.Itoa(int(t)) + ")"
+}
+
+// Parser parses JSON documents into trees of Values without going through
+// map[string]any. The Values of a document, and the memory they refer to,
+// belong to the Parser and are reused by its next Parse, so that parsing
+// documents of similar shape over and over allocates nothing once the
+// Parser has grown to fit them.
+//
+// A Parser, and the Values it returns, must not be used concurrently. The
+// zero Parser is ready for use; GetParser returns a pooled one.
+type Parser struct {
+	it     Iterator
+	data   []byte  // copy of the document; string Values alias it
+	values []Value // arena of the current document's Values
+}
+
+var parserPool = sync.Pool{
+	New: func() any {
+		return new(Parser)
+	},
+}
+
+// GetParser returns a Parser from a pool, with default options.
+func GetParser() *Parser {
+	p := parserPool.Get().(*Parser)
+	p.SetOptions(DecodeOptions{})
+	return p
+}
+
+// PutParser returns p to the pool. Neither p nor its Values may be used
+// afterwards.
+func PutParser(p *Parser) {
+	parserPool.Put(p)
+}
+
+// SetOptions sets the limits Parse enforces: MaxInputSize, MaxDepth and
+// MaxStringLength. The input must always be valid JSON, as under Strict,
+// and other options do not apply.
+func (p *Parser) SetOptions(opts DecodeOptions) {
+	opts.Strict = true
+	p.it.SetOptions(opts)
+}
+
+// Parse parses data, which must hold exactly one JSON value, and returns
+// its root. data is copied, so the caller may reuse it at once; the
+// returned Value stays valid until the next call to Parse.
+func (p *Parser) Parse(data []byte) (*Value, error) {
+	p.data = append(p.data[:0], data...)
+	p.values = p.values[:0]
+	p.it.Reset(p.data)
+	p.it.opts.Strict = true
+	if err := p.it.checkInputSize(); err != nil {
+		return nil, err
+	}
+
+	v, err := p.parseValue()
+	if err == nil {
+		err = p.it.checkEnd()
+	}
+	if err != nil {
+		// The next Parse overwrites p.data, so point the error's position
+		// at the caller's copy of the input.
+		if e, ok := err.(*SyntaxError); ok {
+			e.data = data
+		}
+		return nil, err
+	}
+	return v, nil
+}
+
+// newValue takes a Value of type t from the arena, keeping the capacity of
+// the children slices it had in earlier documents.
+func (p *Parser) newValue(t Type) *Value {
+	if len(p.values) < cap(p.values) {
+		p.values = p.values[:len(p.values)+1]
+	} else {
+		p.values = append(p.values, Value{})
+	}
+	v := &p.values[len(p.values)-1]
+	v.t = t
+	v.raw = nil
+	v.escaped = false
+	v.a = v.a[:0]
+	v.o.kvs = v.o.kvs[:0]
+	return v
+}
+
+func (p *Parser) parseValue() (*Value, error) {
+	it := &p.it
+	it.skipWhiteSpace()
+	if it.head >= it.dataLen {
+		return nil, it.expected("value")
+	}
+
+	switch c := it.data[it.head]; c {
+	case '{':
+		return p.parseObject()
+	case '[':
+		return p.parseArray()
+	case '"':
+		raw, escaped, err := p.parseString()
+		if err != nil {
+			return nil, err
+		}
+		v := p.newValue(TypeString)
+		v.raw, v.escaped = raw, escaped
+		return v, nil
+	case 't':
+		if err := it.skipLiteral("true"); err != nil {
+			return nil, err
+		}
+		return p.newValue(TypeTrue), nil
+	case 'f':
+		if err := it.skipLiteral("false"); err != nil {
+			return nil, err
+		}
+		return p.newValue(TypeFalse), nil
+	case 'n':
+		if err := it.skipLiteral("null"); err != nil {
+			return nil, err
+		}
+		return p.newValue(TypeNull), nil
+	default:
+		if !isNumberStart(c) {
+			return nil, it.expected("value")
+		}
+		start := it.head
+		if err := it.skipNumberStrict(); err != nil {
+			return nil, err
+		}
+		v := p.newValue(TypeNumber)
+		v.raw = it.data[start:it.head]
+		return v, nil
+	}
+}
+
+func (p *Parser) parseObject() (*Value, error) {
+	it := &p.it
+	if err := it.ReadObjectStart(); err != nil {
+		return nil, err
+	}
+	v := p.newValue(TypeObject)
+
+	it.skipWhiteSpace()
+	if it.char() == '}' {
+		it.head++
+		it.depth--
+		return v, nil
+	}
+
+	for {
+		it.skipWhiteSpace()
+		if it.char() != '"' {
+			return nil, it.expected("string")
+		}
+		key, escaped, err := p.parseString()
+		if err != nil {
+			return nil, err
+		}
+		if escaped {
+			// Keys are compared on every lookup, so they are unescaped
+			// right away rather than on first use.
+			key = unescapeInPlace(key)
+		}
+		if err := it.ReadColon(); err != nil {
+			return nil, err
+		}
+
+		child, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		v.o.kvs = append(v.o.kvs, kv{key: key, value: child})
+
+		it.skipWhiteSpace()
+		switch it.char() {
+		case ',':
+			it.head++
+		case '}':
+			it.head++
+			it.depth--
+			return v, nil
+		default:
+			return nil, it.expected("',' or '}'")
+		}
+	}
+}
+
+func (p *Parser) parseArray() (*Value, error) {
+	it := &p.it
+	if err := it.ReadArrayStart(); err != nil {
+		return nil, err
+	}
+	v := p.newValue(TypeArray)
+
+	it.skipWhiteSpace()
+	if it.char() == ']' {
+		it.head++
+		it.depth--
+		return v, nil
+	}
+
+	for {
+		child, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		v.a = append(v.a, child)
+
+		it.skipWhiteSpace()
+		switch it.char() {
+		case ',':
+			it.head++
+		case ']':
+			it.head++
+			it.depth--
+			return v, nil
+		default:
+			return nil, it.expected("',' or ']'")
+		}
+	}
+}
+
+// parseString validates the string at it.head and returns its contents
+// between the quotes, still escaped, and whether they hold any escape.
+func (p *Parser) parseString() ([]byte, bool, error) {
+	it := &p.it
+	start := it.head + 1
+	if err := it.skipStringStrict(); err != nil {
+		return nil, false, err
+	}
+	raw := it.data[start : it.head-1]
+	if max := it.opts.MaxStringLength; max > 0 && len(raw) > max {
+		return nil, false, &LimitError{Limit: "MaxStringLength", Max: max, Offset: start - 1}
+	}
+	return raw, bytes.IndexByte(raw, '\\') >= 0, nil
+}
+
+// Value is a JSON value in a document returned by Parser.Parse. Strings
+// are unescaped and numbers converted only when they are read.
+//
+// The Get methods take a path of object keys and array indexes, written
+// in decimal, and return the zero value when the path does not lead to a
+// value of the wanted type:
+//
+//	id := v.GetInt64("items", "0", "id")
+type Value struct {
+	t Type
+
+	// raw holds the text of a number, or the contents of a string between
+	// its quotes, unescaped in place on first read unless escaped is false.
+	raw     []byte
+	escaped bool
+
+	a []*Value
+	o Object
+}
+
+// Type returns the type of v.
+func (v *Value) Type() Type {
+	return v.t
+}
+
+// Get returns the value at the path keys under v, or nil if there is none.
+// Get on a nil Value returns nil, so lookups can be chained.
+func (v *Value) Get(keys ...string) *Value {
+	for _, key := range keys {
+		if v == nil {
+			return nil
+		}
+		switch v.t {
+		case TypeObject:
+			v = v.o.Get(key)
+		case TypeArray:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v.a) {
+				return nil
+			}
+			v = v.a[i]
+		default:
+			return nil
+		}
+	}
+	return v
+}
+
+// Exists reports whether there is a value at the path keys under v.
+func (v *Value) Exists(keys ...string) bool {
+	return v.Get(keys...) != nil
+}
+
+// GetInt64 returns the integer at the path keys under v, or 0.
+func (v *Value) GetInt64(keys ...string) int64 {
+	n, _ := v.Get(keys...).Int64()
+	return n
+}
+
+// GetFloat64 returns the number at the path keys under v, or 0.
+func (v *Value) GetFloat64(keys ...string) float64 {
+	f, _ := v.Get(keys...).Float64()
+	return f
+}
+
+// GetStringBytes returns the unescaped string at the path keys under v,
+// or nil. The bytes belong to the Parser.
+func (v *Value) GetStringBytes(keys ...string) []byte {
+	b, _ := v.Get(keys...).StringBytes()
+	return b
+}
+
+// GetBool returns the boolean at the path keys under v, or false.
+func (v *Value) GetBool(keys ...string) bool {
+	b, _ := v.Get(keys...).Bool()
+	return b
+}
+
+// Int64 returns the value of an integer number.
+func (v *Value) Int64() (int64, error) {
+	if err := v.check(TypeNumber); err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(bytesToString(v.raw), 10, 64)
+}
+
+// Float64 returns the value of a number.
+func (v *Value) Float64() (float64, error) {
+	if err := v.check(TypeNumber); err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(bytesToString(v.raw), 64)
+}
+
+// StringBytes returns the unescaped contents of a string. The bytes
+// belong to the Parser and must not be modified.
+func (v *Value) StringBytes() ([]byte, error) {
+	if err := v.check(TypeString); err != nil {
+		return nil, err
+	}
+	if v.escaped {
+		v.raw = unescapeInPlace(v.raw)
+		v.escaped = false
+	}
+	return v.raw, nil
+}
+
+// Bool returns the value of true or false.
+func (v *Value) Bool() (bool, error) {
+	if v != nil && v.t == TypeFalse {
+		return false, nil
+	}
+	if err := v.check(TypeTrue); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Array returns the elements of an array. The slice belongs to the Parser.
+func (v *Value) Array() ([]*Value, error) {
+	if err := v.check(TypeArray); err != nil {
+		return nil, err
+	}
+	return v.a, nil
+}
+
+// Object returns the members of an object.
+func (v *Value) Object() (*Object, error) {
+	if err := v.check(TypeObject); err != nil {
+		return nil, err
+	}
+	return &v.o, nil
+}
+
+func (v *Value) check(t Type) error {
+	if v == nil {
+		return &ValueTypeError{Want: t, Missing: true}
+	}
+	if v.t != t {
+		return &ValueTypeError{Want: t, Got: v.t}
+	}
+	return nil
+}
+
+// Object is the set of members of an object Value, in input order.
+type Object struct {
+	kvs []kv
+}
+
+type kv struct {
+	key   []byte
+	value *Value
+}
+
+// Len returns the number of members of o, duplicates included.
+func (o *Object) Len() int {
+	return len(o.kvs)
+}
+
+// Get returns the value of key, or nil. When a key is repeated the last
+// occurrence wins, as with DuplicateKeyLastWins.
+func (o *Object) Get(key string) *Value {
+	for i := len(o.kvs) - 1; i >= 0; i-- {
+		if bytesToString(o.kvs[i].key) == key {
+			return o.kvs[i].value
+		}
+	}
+	return nil
+}
+
+// Visit calls f for each member of o in input order. key belongs to the
+// Parser and must not be modified or kept past the next Parse.
+func (o *Object) Visit(f func(key []byte, v *Value)) {
+	for _, kv := range o.kvs {
+		f(kv.key, kv.value)
+	}
+}
+
+// unescapeInPlace decodes the escapes in b, the contents of a string that
+// skipStringStrict accepted, and returns the shortened b. No escape is
+// shorter than what it stands for, so decoded bytes never overtake the
+// ones still to be read. Unpaired surrogates become U+FFFD.
+func unescapeInPlace(b []byte) []byte {
+	w := bytes.IndexByte(b, '\\')
+	if w < 0 {
+		return b
+	}
+
+	for r := w; r < len(b); {
+		c := b[r]
+		if c != '\\' {
+			b[w] = c
+			w++
+			r++
+			continue
+		}
+
+		switch e := b[r+1]; e {
+		case 'u':
+			x := hexRune(b[r+2 : r+6])
+			r += 6
+			if utf16.IsSurrogate(x) {
+				pair := utf8.RuneError
+				if x < 0xdc00 && r+6 <= len(b) && b[r] == '\\' && b[r+1] == 'u' {
+					if pair = utf16.DecodeRune(x, hexRune(b[r+2:r+6])); pair != utf8.RuneError {
+						r += 6
+					}
+				}
+				x = pair
+			}
+			w += utf8.EncodeRune(b[w:], x)
+		default:
+			switch e {
+			case 'b':
+				e = '\b'
+			case 'f':
+				e = '\f'
+			case 'n':
+				e = '\n'
+			case 'r':
+				e = '\r'
+			case 't':
+				e = '\t'
+			}
+			b[w] = e
+			w++
+			r += 2
+		}
+	}
+	return b[:w]
+}
+
+// hexRune decodes four hex digits that have already been validated.
+func hexRune(b []byte) rune {
+	var r rune
+	for _, c := range b {
+		switch {
+		case c <= '9':
+			c -= '0'
+		case c >= 'a':
+			c -= 'a' - 10
+		default:
+			c -= 'A' - 10
+		}
+		r = r<<4 | rune(c)
+	}
+	return r
+}
@@ -0,0 +1,280 @@
+package fastjson
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const domDoc = `{
+	"id": 42,
+	"name": "café \"latte\"",
+	"price": 3.5,
+	"tags": ["hot", "milk"],
+	"stock": {"store": 12, "online": null, "open": true},
+	"emoji": "😀 \ud800!",
+	"name": "override"
+}`
+
+func TestParser_Accessors(t *testing.T) {
+	var p Parser
+	v, err := p.Parse([]byte(domDoc))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v.Type() != TypeObject {
+		t.Fatalf("Type = %v, want object", v.Type())
+	}
+	if got := v.GetInt64("id"); got != 42 {
+		t.Errorf("GetInt64(id) = %d", got)
+	}
+	if got := v.GetFloat64("price"); got != 3.5 {
+		t.Errorf("GetFloat64(price) = %v", got)
+	}
+	if got := string(v.GetStringBytes("name")); got != "override" {
+		t.Errorf("GetStringBytes(name) = %q, want the last occurrence", got)
+	}
+	if got := string(v.GetStringBytes("tags", "1")); got != "milk" {
+		t.Errorf("GetStringBytes(tags, 1) = %q", got)
+	}
+	if got := string(v.GetStringBytes("emoji")); got != "\U0001F600 �!" {
+		t.Errorf("GetStringBytes(emoji) = %q", got)
+	}
+	if !v.GetBool("stock", "open") || v.Get("stock", "online").Type() != TypeNull {
+		t.Error("stock.open or stock.online read wrong")
+	}
+
+	// Missing paths and wrong types give zero values.
+	if v.Get("stock", "warehouse") != nil || v.Exists("tags", "2") || v.Exists("id", "x") {
+		t.Error("Get found a value that does not exist")
+	}
+	if v.GetInt64("price") != 0 || v.GetStringBytes("id") != nil || v.GetInt64("nope", "deeper") != 0 {
+		t.Error("Get methods did not return zero values")
+	}
+	var typeErr *ValueTypeError
+	if _, err := v.Get("tags").Object(); !errors.As(err, &typeErr) || typeErr.Want != TypeObject || typeErr.Got != TypeArray {
+		t.Errorf("Object() on an array = %v, want a *ValueTypeError", err)
+	}
+	if _, err := v.Get("nope").Bool(); !errors.As(err, &typeErr) || !typeErr.Missing {
+		t.Errorf("Bool() on a missing value = %v, want a *ValueTypeError", err)
+	} else if err.Error() != "fastjson: no value where true or false was expected" {
+		t.Errorf("unexpected message %q", err)
+	}
+
+	tags, err := v.Get("tags").Array()
+	if err != nil || len(tags) != 2 {
+		t.Fatalf("Array() = %v, %v", tags, err)
+	}
+
+	o, err := v.Object()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	o.Visit(func(key []byte, _ *Value) {
+		keys = append(keys, string(key))
+	})
+	want := []string{"id", "name", "price", "tags", "stock", "emoji", "name"}
+	if !reflect.DeepEqual(keys, want) || o.Len() != len(want) {
+		t.Errorf("Visit keys = %v, want %v", keys, want)
+	}
+}
+
+func TestParser_EscapedKeysAndStrings(t *testing.T) {
+	var p Parser
+	v, err := p.Parse([]byte(`{"a\tb":"\\\/\b\f\n\r\t","A":"x\u0000y"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(v.GetStringBytes("a\tb")); got != "\\/\b\f\n\r\t" {
+		t.Errorf("escaped value = %q", got)
+	}
+	if got := string(v.GetStringBytes("A")); got != "x\x00y" {
+		t.Errorf("value of escaped key = %q", got)
+	}
+	// Reading twice must not unescape twice.
+	if got := string(v.GetStringBytes("a\tb")); got != "\\/\b\f\n\r\t" {
+		t.Errorf("second read = %q", got)
+	}
+}
+
+func TestParser_MatchesUnmarshal(t *testing.T) {
+	docs := []string{
+		domDoc,
+		`[]`,
+		`{}`,
+		`[1, -0.5e3, "", [[]], {"a": {"b": [false]}}]`,
+		` "é" `,
+		`null`,
+	}
+	var p Parser
+	for _, doc := range docs {
+		v, err := p.Parse([]byte(doc))
+		if err != nil {
+			t.Errorf("Parse(%s): %v", doc, err)
+			continue
+		}
+		var want any
+		if err := Unmarshal([]byte(doc), &want); err != nil {
+			t.Fatal(err)
+		}
+		if got := domToAny(t, v); !reflect.DeepEqual(got, want) {
+			t.Errorf("Parse(%s) = %#v, want %#v", doc, got, want)
+		}
+	}
+}
+
+func domToAny(t *testing.T, v *Value) any {
+	switch v.Type() {
+	case TypeObject:
+		o, _ := v.Object()
+		m := make(map[string]any, o.Len())
+		o.Visit(func(key []byte, v *Value) {
+			m[string(key)] = domToAny(t, v)
+		})
+		return m
+	case TypeArray:
+		a, _ := v.Array()
+		s := make([]any, 0, len(a))
+		for _, e := range a {
+			s = append(s, domToAny(t, e))
+		}
+		return s
+	case TypeString:
+		b, _ := v.StringBytes()
+		return string(b)
+	case TypeNumber:
+		f, err := v.Float64()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return f
+	case TypeTrue, TypeFalse:
+		b, _ := v.Bool()
+		return b
+	default:
+		return nil
+	}
+}
+
+func TestParser_Errors(t *testing.T) {
+	tests := []struct {
+		in   string
+		opts DecodeOptions
+		want string
+	}{
+		{``, DecodeOptions{}, "expected value"},
+		{`{"a":1,}`, DecodeOptions{}, "expected string"},
+		{`[1,]`, DecodeOptions{}, "expected value"},
+		{`[1 2]`, DecodeOptions{}, "expected ',' or ']'"},
+		{`{"a" 1}`, DecodeOptions{}, "expected ':'"},
+		{`01`, DecodeOptions{}, "unexpected data after top-level value"},
+		{`"a\x"`, DecodeOptions{}, "invalid escape sequence"},
+		{`tru`, DecodeOptions{}, "expected 'true'"},
+		{`{} {}`, DecodeOptions{}, "unexpected data after top-level value"},
+		{`[[[1]]]`, DecodeOptions{MaxDepth: 2}, "max nesting depth"},
+		{`"abcdef"`, DecodeOptions{MaxStringLength: 3}, "MaxStringLength"},
+		{`[1,2,3]`, DecodeOptions{MaxInputSize: 4}, "MaxInputSize"},
+	}
+	var p Parser
+	for _, tt := range tests {
+		p.SetOptions(tt.opts)
+		_, err := p.Parse([]byte(tt.in))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Parse(%q) = %v, want error containing %q", tt.in, err, tt.want)
+		}
+	}
+}
+
+func TestParser_ErrorOutlivesReuse(t *testing.T) {
+	var p Parser
+	_, err := p.Parse([]byte("[1,\n 2 3]"))
+	var synErr *SyntaxError
+	if !errors.As(err, &synErr) {
+		t.Fatalf("expected *SyntaxError, got %v", err)
+	}
+	if _, err := p.Parse([]byte("[\"a much longer document\"]")); err != nil {
+		t.Fatal(err)
+	}
+	if synErr.Line() != 2 || synErr.Snippet() != " 2 3]\n   ^" {
+		t.Errorf("error changed with the parser's buffer: line %d\n%s", synErr.Line(), synErr.Snippet())
+	}
+}
+
+func TestParser_ReuseCopiesInput(t *testing.T) {
+	p := GetParser()
+	defer PutParser(p)
+
+	data := []byte(`{"name":"first"}`)
+	v, err := p.Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	copy(data, `{"name":"XXXXX"}`)
+	if got := string(v.GetStringBytes("name")); got != "first" {
+		t.Errorf("value changed with the input: %q", got)
+	}
+
+	v, err = p.Parse([]byte(`[{"name":"second"}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(v.GetStringBytes("0", "name")); got != "second" {
+		t.Errorf("after reuse: %q", got)
+	}
+}
+
+func TestParser_ZeroAllocs(t *testing.T) {
+	docs := [][]byte{
+		[]byte(domDoc),
+		[]byte(`{"id": 7, "name": "tea", "tags": ["cold"], "stock": {"store": 1}}`),
+	}
+	var p Parser
+	for _, doc := range docs {
+		if _, err := p.Parse(doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	i := 0
+	allocs := testing.AllocsPerRun(100, func() {
+		v, err := p.Parse(docs[i%len(docs)])
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = v.GetStringBytes("name")
+		_ = v.GetInt64("stock", "store")
+		i++
+	})
+	if allocs != 0 {
+		t.Errorf("Parse allocated %v times per run, want 0", allocs)
+	}
+}
+
+func BenchmarkParser(b *testing.B) {
+	data := []byte(domDoc)
+	b.Run("Parser", func(b *testing.B) {
+		var p Parser
+		b.ReportAllocs()
+		b.SetBytes(int64(len(data)))
+		for b.Loop() {
+			v, err := p.Parse(data)
+			if err != nil {
+				b.Fatal(err)
+			}
+			_ = v.GetInt64("stock", "store")
+		}
+	})
+	b.Run("UnmarshalAny", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(data)))
+		for b.Loop() {
+			var v any
+			if err := Unmarshal(data, &v); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -1,32 +1,41 @@
 package fastjson
 
 import (
-	"fmt"
+	"math"
 	"reflect"
+	"slices"
 	"strings"
-	"sync"
 	"unsafe"
 )
 
 type EncoderFunc func(w *Writer, p unsafe.Pointer) error
 
-var encoderCache sync.Map
-
-func getEncoder(t reflect.Type) (EncoderFunc, error) {
-	if f, ok := encoderCache.Load(t); ok {
-		return f.(EncoderFunc), nil
+func (a *API) getEncoder(t reflect.Type) (EncoderFunc, error) {
+	gen := a.generation()
+	if f, ok := a.encoders.Load(t); ok && f.(cachedEncoder).gen == gen {
+		return f.(cachedEncoder).enc, nil
 	}
 
-	enc, err := compileEncoder(t)
+	enc, err := a.compileEncoder(t)
 	if err != nil {
 		return nil, err
 	}
 
-	encoderCache.Store(t, enc)
+	a.encoders.Store(t, cachedEncoder{gen: gen, enc: enc})
 	return enc, nil
 }
 
-func compileEncoder(t reflect.Type) (EncoderFunc, error) {
+func (a *API) compileEncoder(t reflect.Type) (EncoderFunc, error) {
+	if enc, ok := a.registeredEncoder(t); ok {
+		return enc, nil
+	}
+	if t == numberType {
+		return encodeNumber, nil
+	}
+	if !a.ignoreMarshalers && reflect.PointerTo(t).Implements(marshalerType) {
+		return marshalerEncoder(t), nil
+	}
+
 	switch t.Kind() {
 	case reflect.String:
 		return encodeString, nil
@@ -39,15 +48,21 @@ func compileEncoder(t reflect.Type) (EncoderFunc, error) {
 	case reflect.Bool:
 		return encodeBool, nil
 	case reflect.Interface:
-		return encodeInterface, nil
+		if u, ok := a.registeredUnion(t); ok {
+			return a.compileUnionEncoder(t, u)
+		}
+		if t.NumMethod() > 0 {
+			return a.methodInterfaceEncoder(t), nil
+		}
+		return a.encodeInterface, nil
 	case reflect.Struct:
-		return compileStructEncoder(t)
+		return a.compileStructEncoder(t, nil)
 	case reflect.Slice:
-		return compileSliceEncoderEnc(t)
+		return a.compileSliceEncoderEnc(t)
 	case reflect.Map:
-		return compileMapEncoder(t)
+		return a.compileMapEncoder(t)
 	case reflect.Pointer:
-		elemEnc, err := compileEncoder(t.Elem())
+		elemEnc, err := a.compileEncoder(t.Elem())
 		if err != nil {
 			return nil, err
 		}
@@ -60,7 +75,7 @@ func compileEncoder(t reflect.Type) (EncoderFunc, error) {
 			return elemEnc(w, ptrVal)
 		}, nil
 	default:
-		return nil, fmt.Errorf("fastjson: unsupported type: %s", t.Kind())
+		return nil, &UnsupportedTypeError{Type: t, Reason: t.Kind().String() + " values are not supported"}
 	}
 }
 
@@ -81,7 +96,16 @@ func encodeInt32(w *Writer, p unsafe.Pointer) error {
 }
 
 func encodeFloat64(w *Writer, p unsafe.Pointer) error {
-	w.WriteFloat64(*(*float64)(p))
+	return w.EncodeFloat64(*(*float64)(p))
+}
+
+// EncodeFloat64 writes f like WriteFloat64, but fails on NaN and infinities
+// under NonFiniteError, as the compiled encoders do.
+func (w *Writer) EncodeFloat64(f float64) error {
+	if w.opts.NonFinite == NonFiniteError && (math.IsNaN(f) || math.IsInf(f, 0)) {
+		return unsupportedFloat(f)
+	}
+	w.WriteFloat64(f)
 	return nil
 }
 
@@ -92,17 +116,32 @@ func encodeBool(w *Writer, p unsafe.Pointer) error {
 
 // Dynamic encoders
 
-func encodeInterface(w *Writer, p unsafe.Pointer) error {
+func (a *API) encodeInterface(w *Writer, p unsafe.Pointer) error {
 	val := *(*any)(p)
 	if val == nil {
 		w.WriteNull()
 		return nil
 	}
+	return a.encodeDynamic(w, reflect.ValueOf(val))
+}
+
+// methodInterfaceEncoder encodes interfaces with methods, whose memory
+// layout differs from that of any.
+func (a *API) methodInterfaceEncoder(t reflect.Type) EncoderFunc {
+	return func(w *Writer, p unsafe.Pointer) error {
+		rv := reflect.NewAt(t, p).Elem()
+		if rv.IsNil() {
+			w.WriteNull()
+			return nil
+		}
+		return a.encodeDynamic(w, rv.Elem())
+	}
+}
 
-	rv := reflect.ValueOf(val)
+// encodeDynamic encodes rv, the concrete value held by an interface.
+func (a *API) encodeDynamic(w *Writer, rv reflect.Value) error {
 	rt := rv.Type()
-
-	enc, err := getEncoder(rt)
+	enc, err := a.getEncoder(rt)
 	if err != nil {
 		return err
 	}
@@ -110,7 +149,9 @@ func encodeInterface(w *Writer, p unsafe.Pointer) error {
 	var ptr unsafe.Pointer
 
 	if rt.Kind() == reflect.Pointer {
-		ptr = unsafe.Pointer(rv.Pointer())
+		// enc expects the address of the pointer, not the pointer itself.
+		pv := rv.UnsafePointer()
+		ptr = unsafe.Pointer(&pv)
 	} else {
 		newPtr := reflect.New(rt)
 		newPtr.Elem().Set(rv)
@@ -124,30 +165,70 @@ func encodeInterface(w *Writer, p unsafe.Pointer) error {
 type structFieldEncoder struct {
 	offset  uintptr
 	encoder EncoderFunc
-	key     []byte // preformatted key
+	key     []byte // preformatted key, nil when rawName needs escaping
+	name    []byte // quoted name alone, for indented output
+	rawName string
+	first   bool
 }
 
-func compileStructEncoder(t reflect.Type) (EncoderFunc, error) {
+// writeName writes the field's quoted name, escaping it as w's options ask
+// when it could not be preformatted.
+func (f *structFieldEncoder) writeName(w *Writer) {
+	if f.name != nil {
+		w.Write(f.name)
+	} else {
+		w.WriteStringEscaped(f.rawName)
+	}
+}
+
+// plainKey reports whether name needs no escaping under any EncodeOptions,
+// so that its quoted form can be computed once at compile time.
+func plainKey(name string) bool {
+	for i := 0; i < len(name); i++ {
+		if htmlUTF8Table[name[i]] != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// setName precomputes the key of a field named name, unless the name needs
+// escaping, which is then left to writeName.
+func (f *structFieldEncoder) setName(name string, first bool) {
+	f.rawName, f.first = name, first
+	if !plainKey(name) {
+		return
+	}
+	f.name = []byte(`"` + name + `"`)
+	if first {
+		f.key = []byte(`"` + name + `":`)
+	} else {
+		f.key = []byte(`,"` + name + `":`)
+	}
+}
+
+// compileStructEncoder compiles an encoder for struct type t. lead, when not
+// nil, is written before the fields, as unions do with their discriminator.
+func (a *API) compileStructEncoder(t reflect.Type, lead *structFieldEncoder) (EncoderFunc, error) {
 	var fields []structFieldEncoder
+	if lead != nil {
+		fields = append(fields, *lead)
+	}
 
 	for i := range t.NumField() {
 		field := t.Field(i)
-		tag := field.Tag.Get("json")
-		if tag == "-" {
+		tag := parseTag(field, a.naming)
+		if tag.Skip {
 			continue
 		}
+		name := tag.Name
 
-		name := field.Name
-		if tag != "" {
-			parts := strings.Split(tag, ",")
-			if parts[0] != "" {
-				name = parts[0]
-			}
-		}
-
-		enc, err := compileEncoder(field.Type)
+		enc, err := a.compileEncoder(field.Type)
 		if err != nil {
-			return nil, err
+			return nil, withTypePath(err, t, "."+field.Name)
+		}
+		if tag.Quoted && isQuotable(field.Type) {
+			enc = quotedEncoder(enc)
 		}
 
 		// Prepare key bytes
@@ -158,25 +239,28 @@ func compileStructEncoder(t reflect.Type) (EncoderFunc, error) {
 		// which fields are empty (if omitempty).
 		// For this MVP, we ignore omitempty and assume standard strict JSON.
 
-		var keyBytes []byte
-		if len(fields) > 0 {
-			keyBytes = []byte(`,"` + name + `":`)
-		} else {
-			keyBytes = []byte(`"` + name + `":`)
-		}
-
-		fields = append(fields, structFieldEncoder{
-			offset:  field.Offset,
-			encoder: enc,
-			key:     keyBytes,
-		})
+		f := structFieldEncoder{offset: field.Offset, encoder: enc}
+		f.setName(name, len(fields) == 0)
+		fields = append(fields, f)
 	}
 
 	return func(w *Writer, p unsafe.Pointer) error {
+		if w.pretty {
+			return encodeStructIndent(w, p, fields)
+		}
+
 		w.WriteByte('{')
 		for i := range fields {
 			f := &fields[i]
-			w.Write(f.key)
+			if f.key != nil {
+				w.Write(f.key)
+			} else {
+				if !f.first {
+					w.WriteByte(',')
+				}
+				f.writeName(w)
+				w.WriteByte(':')
+			}
 			fieldPrt := unsafe.Pointer(uintptr(p) + f.offset)
 			if err := f.encoder(w, fieldPrt); err != nil {
 				return err
@@ -188,12 +272,47 @@ func compileStructEncoder(t reflect.Type) (EncoderFunc, error) {
 	}, nil
 }
 
-func compileSliceEncoderEnc(t reflect.Type) (EncoderFunc, error) {
+// quotedEncoder writes the output of enc inside a JSON string.
+func quotedEncoder(enc EncoderFunc) EncoderFunc {
+	return func(w *Writer, p unsafe.Pointer) error {
+		w.WriteByte('"')
+		if err := enc(w, p); err != nil {
+			return err
+		}
+		w.WriteByte('"')
+		return nil
+	}
+}
+
+func encodeStructIndent(w *Writer, p unsafe.Pointer, fields []structFieldEncoder) error {
+	if len(fields) == 0 {
+		w.WriteString("{}")
+		return nil
+	}
+
+	w.openIndent('{')
+	for i := range fields {
+		f := &fields[i]
+		if i > 0 {
+			w.WriteByte(',')
+		}
+		w.writeIndent()
+		f.writeName(w)
+		w.WriteString(": ")
+		if err := f.encoder(w, unsafe.Pointer(uintptr(p)+f.offset)); err != nil {
+			return err
+		}
+	}
+	w.closeIndent('}')
+	return nil
+}
+
+func (a *API) compileSliceEncoderEnc(t reflect.Type) (EncoderFunc, error) {
 	elemType := t.Elem()
 	elemSize := elemType.Size()
-	elemEnc, err := compileEncoder(elemType)
+	elemEnc, err := a.compileEncoder(elemType)
 	if err != nil {
-		return nil, err
+		return nil, withTypePath(err, t, "[]")
 	}
 
 	return func(w *Writer, p unsafe.Pointer) error {
@@ -204,12 +323,23 @@ func compileSliceEncoderEnc(t reflect.Type) (EncoderFunc, error) {
 			return nil
 		}
 
-		w.WriteByte('[')
+		if w.pretty {
+			if header.Len == 0 {
+				w.WriteString("[]")
+				return nil
+			}
+			w.openIndent('[')
+		} else {
+			w.WriteByte('[')
+		}
 
 		for i := range header.Len {
 			if i > 0 {
 				w.WriteByte(',')
 			}
+			if w.pretty {
+				w.writeIndent()
+			}
 
 			elemPtr := unsafe.Pointer(uintptr(header.Data) + uintptr(i)*elemSize)
 			if err := elemEnc(w, elemPtr); err != nil {
@@ -217,19 +347,23 @@ func compileSliceEncoderEnc(t reflect.Type) (EncoderFunc, error) {
 			}
 		}
 
-		w.WriteByte(']')
+		if w.pretty {
+			w.closeIndent(']')
+		} else {
+			w.WriteByte(']')
+		}
 		return nil
 	}, nil
 }
 
-func compileMapEncoder(t reflect.Type) (EncoderFunc, error) {
+func (a *API) compileMapEncoder(t reflect.Type) (EncoderFunc, error) {
 	if t.Key().Kind() != reflect.String {
-		return nil, fmt.Errorf("fastjson: maps with %s keys not supported", t.Key().Kind())
+		return nil, &UnsupportedTypeError{Type: t, Reason: "map keys must be strings"}
 	}
 
-	elemEnv, err := compileEncoder(t.Elem())
+	elemEnv, err := a.compileEncoder(t.Elem())
 	if err != nil {
-		return nil, err
+		return nil, withTypePath(err, t, "[]")
 	}
 
 	return func(w *Writer, p unsafe.Pointer) error {
@@ -239,36 +373,58 @@ func compileMapEncoder(t reflect.Type) (EncoderFunc, error) {
 			return nil
 		}
 
-		w.WriteByte('{')
-		iter := mVal.MapRange()
-		first := true
-
-		for iter.Next() {
+		if w.pretty {
+			if mVal.Len() == 0 {
+				w.WriteString("{}")
+				return nil
+			}
+			w.openIndent('{')
+		} else {
+			w.WriteByte('{')
+		}
+		elem := reflect.New(t.Elem())
+		writeEntry := func(key string, val reflect.Value, first bool) error {
 			if !first {
 				w.WriteByte(',')
 			}
-			first = false
-
-			w.WriteStringEscaped(iter.Key().String())
-			w.WriteByte(':')
-
-			val := iter.Value()
-			var valPtr unsafe.Pointer
-
-			if t.Elem().Kind() == reflect.Pointer {
-				valPtr = unsafe.Pointer(val.Pointer())
+			if w.pretty {
+				w.writeIndent()
+				w.WriteStringEscaped(key)
+				w.WriteString(": ")
 			} else {
-				newPtr := reflect.New(t.Elem())
-				newPtr.Elem().Set(val)
-				valPtr = unsafe.Pointer(newPtr.Pointer())
+				w.WriteStringEscaped(key)
+				w.WriteByte(':')
 			}
 
-			if err := elemEnv(w, valPtr); err != nil {
-				return err
+			// Map values are not addressable, so encode from a copy.
+			elem.Elem().Set(val)
+			return elemEnv(w, elem.UnsafePointer())
+		}
+
+		if w.opts.SortMapKeys {
+			keys := mVal.MapKeys()
+			slices.SortFunc(keys, func(a, b reflect.Value) int {
+				return strings.Compare(a.String(), b.String())
+			})
+			for i, k := range keys {
+				if err := writeEntry(k.String(), mVal.MapIndex(k), i == 0); err != nil {
+					return err
+				}
+			}
+		} else {
+			iter := mVal.MapRange()
+			for first := true; iter.Next(); first = false {
+				if err := writeEntry(iter.Key().String(), iter.Value(), first); err != nil {
+					return err
+				}
 			}
 		}
 
-		w.WriteByte('}')
+		if w.pretty {
+			w.closeIndent('}')
+		} else {
+			w.WriteByte('}')
+		}
 		return nil
 	}, nil
 }
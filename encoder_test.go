@@ -2,6 +2,8 @@ package fastjson
 
 import (
 	"encoding/json"
+	"errors"
+	"math"
 	"reflect"
 	"testing"
 )
@@ -154,3 +156,71 @@ func TestMarshal_AnonymousStructs(t *testing.T) {
 		t.Errorf("Expected %s, got %s", expected, string(data))
 	}
 }
+
+func TestMarshalIndent(t *testing.T) {
+	input := struct {
+		Nested Nested            `json:"nested"`
+		List   []int             `json:"list"`
+		Empty  []int             `json:"empty"`
+		Nil    []int             `json:"nil"`
+		Dict   map[string]string `json:"dict"`
+		None   map[string]string `json:"none"`
+		Any    any               `json:"any"`
+		Inner  struct{}          `json:"inner"`
+	}{
+		Nested: Nested{Title: "t", Prim: Primitives{S: "s", I: 1}},
+		List:   []int{1, 2},
+		Empty:  []int{},
+		Dict:   map[string]string{"k": "v"},
+		None:   map[string]string{},
+		Any:    []any{"x", map[string]any{"y": true}},
+	}
+
+	for _, indent := range []struct{ prefix, indent string }{{"", "  "}, {">", "\t"}} {
+		expected, _ := json.MarshalIndent(input, indent.prefix, indent.indent)
+		data, err := MarshalIndent(&input, indent.prefix, indent.indent)
+		if err != nil {
+			t.Fatalf("MarshalIndent failed: %v", err)
+		}
+		if string(data) != string(expected) {
+			t.Errorf("Expected:\n%s\nGot:\n%s", expected, data)
+		}
+	}
+}
+
+func TestWriteFloat64(t *testing.T) {
+	inputs := []float64{0, 1, -1.5, 3.14159, 1e20, 1e21, 1e300, 1e-6, 1e-7, 1e-20, -2.5e-9, 123456789.125, math.MaxFloat64, math.SmallestNonzeroFloat64}
+
+	for _, f := range inputs {
+		expected, _ := json.Marshal(f)
+		data, err := Marshal(f)
+		if err != nil {
+			t.Fatalf("Marshal(%v) failed: %v", f, err)
+		}
+		if string(data) != string(expected) {
+			t.Errorf("Expected %s, got %s", expected, data)
+		}
+	}
+}
+
+func TestMarshal_NonFinite(t *testing.T) {
+	input := struct {
+		F float64 `json:"f"`
+	}{F: math.Inf(-1)}
+
+	_, err := Marshal(&input)
+	var valErr *UnsupportedValueError
+	if !errors.As(err, &valErr) || valErr.Str != "-Inf" {
+		t.Errorf("expected *UnsupportedValueError, got %v", err)
+	}
+
+	data, err := MarshalWithOptions(&input, EncodeOptions{NonFinite: NonFiniteString})
+	if err != nil || string(data) != `{"f":"-Infinity"}` {
+		t.Errorf("unexpected result %s, %v", data, err)
+	}
+
+	data, err = MarshalWithOptions([]float64{math.NaN()}, EncodeOptions{NonFinite: NonFiniteNull})
+	if err != nil || string(data) != `[null]` {
+		t.Errorf("unexpected result %s, %v", data, err)
+	}
+}
@@ -0,0 +1,396 @@
+package fastjson
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// SyntaxError describes malformed JSON input. Its position is kept as a
+// byte offset; Line, Column and Snippet work out the rest from the input
+// when asked, so errors that are caught and discarded stay cheap. The error
+// holds on to the input for this.
+type SyntaxError struct {
+	Msg    string
+	Offset int
+
+	// Expected names the token the parser wanted, when known, and Found
+	// describes what it saw instead ("end of input" at EOF).
+	Expected string
+	Found    string
+
+	data []byte
+}
+
+func (e *SyntaxError) Error() string {
+	line, col := e.position()
+	return fmt.Sprintf("fastjson: %s at line %d, column %d (offset %d)", e.Msg, line, col, e.Offset)
+}
+
+// Line returns the 1-based line of the error.
+func (e *SyntaxError) Line() int {
+	line, _ := e.position()
+	return line
+}
+
+// Column returns the 1-based column of the error, in bytes.
+func (e *SyntaxError) Column() int {
+	_, col := e.position()
+	return col
+}
+
+func (e *SyntaxError) position() (line, col int) {
+	lineStart := e.lineStart()
+	return bytes.Count(e.data[:lineStart], []byte{'\n'}) + 1, e.Offset - lineStart + 1
+}
+
+func (e *SyntaxError) lineStart() int {
+	return bytes.LastIndexByte(e.data[:e.Offset], '\n') + 1
+}
+
+// snippetRadius bounds how much of a long line is rendered on each side of
+// the error position.
+const snippetRadius = 32
+
+// Snippet returns the offending line (trimmed around Offset) followed by a
+// second line with a caret under the error position.
+func (e *SyntaxError) Snippet() string {
+	data, offset := e.data, e.Offset
+	lineStart := e.lineStart()
+	lineEnd := len(data)
+	if i := bytes.IndexByte(data[offset:], '\n'); i >= 0 {
+		lineEnd = offset + i
+	}
+
+	from := max(lineStart, offset-snippetRadius)
+	to := min(lineEnd, offset+snippetRadius)
+
+	var sb strings.Builder
+	if from > lineStart {
+		sb.WriteString("...")
+	}
+	line := bytes.TrimRight(data[from:to], "\r")
+	sb.Write(line)
+	if to < lineEnd {
+		sb.WriteString("...")
+	}
+	sb.WriteByte('\n')
+	if from > lineStart {
+		sb.WriteString("   ")
+	}
+	// Keep tabs so the caret lines up with the text above it.
+	for _, c := range data[from:offset] {
+		if c == '\t' {
+			sb.WriteByte('\t')
+		} else if c < utf8.RuneSelf || utf8.RuneStart(c) {
+			sb.WriteByte(' ')
+		}
+	}
+	sb.WriteByte('^')
+	return sb.String()
+}
+
+func newSyntaxError(data []byte, offset int, msg, expected string) *SyntaxError {
+	offset = min(max(offset, 0), len(data))
+	return &SyntaxError{
+		Msg:      msg,
+		Offset:   offset,
+		Expected: expected,
+		Found:    describeToken(data, offset),
+		data:     data,
+	}
+}
+
+func describeToken(data []byte, offset int) string {
+	if offset >= len(data) {
+		return "end of input"
+	}
+	r, _ := utf8.DecodeRune(data[offset:])
+	return strconv.QuoteRune(r)
+}
+
+// UnsupportedValueError is returned when encoding a value that has no JSON
+// representation, such as NaN under NonFiniteError.
+type UnsupportedValueError struct {
+	Value reflect.Value
+	Str   string
+}
+
+func (e *UnsupportedValueError) Error() string {
+	return "fastjson: unsupported value: " + e.Str
+}
+
+// InvalidUTF8Error is returned when encoding a string that is not valid
+// UTF-8 under EncodeOptions.InvalidUTF8 = UTF8Reject. Offset is the index
+// of the first invalid byte in Str.
+type InvalidUTF8Error struct {
+	Str    string
+	Offset int
+}
+
+func (e *InvalidUTF8Error) Error() string {
+	return fmt.Sprintf("fastjson: invalid UTF-8 at byte %d of string %q", e.Offset, e.Str)
+}
+
+func unsupportedFloat(f float64) error {
+	return &UnsupportedValueError{Value: reflect.ValueOf(f), Str: strconv.FormatFloat(f, 'g', -1, 64)}
+}
+
+// UnsupportedTypeError is returned when no codec can be compiled for a type.
+// Path locates Type within Root, the type compilation started from, in Go
+// terms: Order.Lines[].Tags is the Tags field of an element of the Lines
+// field of an Order. Map values are written as [] too.
+type UnsupportedTypeError struct {
+	Type   reflect.Type
+	Root   reflect.Type
+	Path   string
+	Reason string
+}
+
+func (e *UnsupportedTypeError) Error() string {
+	if e.Root == nil {
+		return fmt.Sprintf("fastjson: unsupported type %s: %s", e.Type, e.Reason)
+	}
+	root := e.Root.Name()
+	if root == "" {
+		root = e.Root.String()
+	}
+	return fmt.Sprintf("fastjson: unsupported type %s at %s%s: %s", e.Type, root, e.Path, e.Reason)
+}
+
+// withTypePath extends the path of an *UnsupportedTypeError with segment,
+// the location of the failing type within owner, as compilation unwinds.
+// Other errors pass through.
+func withTypePath(err error, owner reflect.Type, segment string) error {
+	if e, ok := err.(*UnsupportedTypeError); ok {
+		e.Path = segment + e.Path
+		e.Root = owner
+	}
+	return err
+}
+
+// InvalidUnmarshalError describes an invalid argument passed to Unmarshal.
+type InvalidUnmarshalError struct {
+	Type reflect.Type
+}
+
+func (e *InvalidUnmarshalError) Error() string {
+	if e.Type == nil {
+		return "fastjson: Unmarshal(nil)"
+	}
+	if e.Type.Kind() != reflect.Pointer {
+		return "fastjson: Unmarshal(non-pointer " + e.Type.String() + ")"
+	}
+	return "fastjson: Unmarshal(nil " + e.Type.String() + ")"
+}
+
+// DuplicateKeyError is returned under DuplicateKeyReject when an object
+// contains the same key twice.
+type DuplicateKeyError struct {
+	Key    string
+	First  int // offset of the first occurrence
+	Offset int // offset of the repeated occurrence
+}
+
+func (e *DuplicateKeyError) Error() string {
+	return fmt.Sprintf("fastjson: duplicate key %q at offset %d (first seen at offset %d)", e.Key, e.Offset, e.First)
+}
+
+// UnknownFieldError is returned under DecodeOptions.DisallowUnknownFields
+// when an object has a key that matches no field of the struct it is
+// decoded into.
+type UnknownFieldError struct {
+	Key    string
+	Struct string
+	Offset int
+}
+
+func (e *UnknownFieldError) Error() string {
+	return fmt.Sprintf("fastjson: unknown field %q in %s at offset %d", e.Key, e.Struct, e.Offset)
+}
+
+// RequiredFieldError is returned when an object lacks the key of a struct
+// field tagged `fastjson:",required"`.
+type RequiredFieldError struct {
+	Key    string
+	Struct string
+	Field  string
+	Offset int // end of the object
+}
+
+func (e *RequiredFieldError) Error() string {
+	return fmt.Sprintf("fastjson: missing required key %q for field %s.%s at offset %d", e.Key, e.Struct, e.Field, e.Offset)
+}
+
+// UnknownVariantError is returned when an object decoded into a union's
+// interface type lacks the discriminator key or has an unregistered value.
+type UnknownVariantError struct {
+	Interface reflect.Type
+	Key       string
+	Value     string
+	Found     bool // whether the key was present
+	Offset    int
+}
+
+func (e *UnknownVariantError) Error() string {
+	if !e.Found {
+		return fmt.Sprintf("fastjson: missing discriminator %q for %s at offset %d", e.Key, e.Interface, e.Offset)
+	}
+	return fmt.Sprintf("fastjson: unknown %q value %q for %s at offset %d", e.Key, e.Value, e.Interface, e.Offset)
+}
+
+// MaxDepthError is returned when input nests objects and arrays more deeply
+// than DecodeOptions.MaxDepth allows.
+type MaxDepthError struct {
+	Max    int
+	Offset int
+}
+
+func (e *MaxDepthError) Error() string {
+	return fmt.Sprintf("fastjson: exceeded max nesting depth of %d at offset %d", e.Max, e.Offset)
+}
+
+// LimitError is returned when input exceeds one of the resource limits in
+// DecodeOptions. Limit names the DecodeOptions field that was exceeded.
+type LimitError struct {
+	Limit  string
+	Max    int
+	Offset int
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("fastjson: %s of %d exceeded at offset %d", e.Limit, e.Max, e.Offset)
+}
+
+// ValueTypeError is returned by the accessors of a Value, such as Int64,
+// when the value is not of the type they read. Missing is set for the nil
+// Value that Get returns for a path that does not exist; Got is then
+// meaningless. Bool reports Want as TypeTrue.
+type ValueTypeError struct {
+	Want    Type
+	Got     Type
+	Missing bool
+}
+
+func (e *ValueTypeError) Error() string {
+	want := e.Want.String()
+	if e.Want == TypeTrue || e.Want == TypeFalse {
+		want = "true or false"
+	}
+	if e.Missing {
+		return fmt.Sprintf("fastjson: no value where %s was expected", want)
+	}
+	return fmt.Sprintf("fastjson: value is %s, not %s", e.Got, want)
+}
+
+// UnmarshalTypeError describes a JSON value that cannot be stored in the Go
+// value it was decoded into. Struct, Field and Path are filled in as the
+// error unwinds through the enclosing decoders, so the happy path carries no
+// bookkeeping.
+type UnmarshalTypeError struct {
+	Value  string       // JSON value kind, e.g. "string" or "number 1.5"
+	Type   reflect.Type // Go type it could not be assigned to
+	Offset int          // offset of the offending value
+	Struct string       // name of the outermost named struct type containing the field
+	Field  string       // Go field chain from the outermost struct, e.g. "Data.Roles"
+	Path   string       // JSON path from the root, e.g. "$.data[37].roles[1]"
+}
+
+func (e *UnmarshalTypeError) Error() string {
+	if e.Struct != "" {
+		return fmt.Sprintf("fastjson: cannot unmarshal %s into Go struct field %s.%s of type %s at %s",
+			e.Value, e.Struct, e.Field, e.Type, e.Path)
+	}
+	return fmt.Sprintf("fastjson: cannot unmarshal %s into Go value of type %s at %s", e.Value, e.Type, e.Path)
+}
+
+// typeError reports that the value at the current position cannot be decoded
+// into t. The value is left unconsumed so that callers may skip it. Input
+// that is not a JSON value at all is a syntax error instead.
+func (it *Iterator) typeError(t reflect.Type) error {
+	it.skipWhiteSpace()
+
+	var kind string
+	switch c := it.char(); {
+	case c == '"':
+		kind = "string"
+	case c == '{':
+		kind = "object"
+	case c == '[':
+		kind = "array"
+	case c == 't' || c == 'f':
+		kind = "bool"
+	case c == 'n':
+		kind = "null"
+	case isNumberStart(c):
+		end := it.head
+		for end < it.dataLen && parseTable[it.data[end]]&maskNumber != 0 {
+			end++
+		}
+		kind = "number " + string(it.data[it.head:end])
+	default:
+		return it.expected("value")
+	}
+
+	return &UnmarshalTypeError{Value: kind, Type: t, Offset: it.head, Path: "$"}
+}
+
+// The helpers below extend an *UnmarshalTypeError with the location of the
+// enclosing container as it propagates outwards. Other errors pass through.
+
+func withFieldPath(err error, structType reflect.Type, field, key string) error {
+	e, ok := err.(*UnmarshalTypeError)
+	if !ok {
+		return err
+	}
+	// An anonymous struct keeps the name of the named struct inside it,
+	// and only stands in for one when there is none.
+	if name := structType.Name(); name != "" {
+		e.Struct = name
+	} else if e.Struct == "" {
+		e.Struct = structType.String()
+	}
+	if e.Field == "" {
+		e.Field = field
+	} else {
+		e.Field = field + "." + e.Field
+	}
+	e.Path = "$" + keySegment(key) + e.Path[1:]
+	return e
+}
+
+func withIndexPath(err error, i int) error {
+	e, ok := err.(*UnmarshalTypeError)
+	if !ok {
+		return err
+	}
+	e.Path = "$[" + strconv.Itoa(i) + "]" + e.Path[1:]
+	return e
+}
+
+func withKeyPath(err error, key string) error {
+	e, ok := err.(*UnmarshalTypeError)
+	if !ok {
+		return err
+	}
+	e.Path = "$" + keySegment(key) + e.Path[1:]
+	return e
+}
+
+// keySegment renders key as a JSON path member, falling back to bracket
+// notation when it is not a plain identifier.
+func keySegment(key string) string {
+	if key == "" {
+		return `[""]`
+	}
+	for i, r := range key {
+		if r != '_' && !unicode.IsLetter(r) && (i == 0 || !unicode.IsDigit(r)) {
+			return "[" + strconv.Quote(key) + "]"
+		}
+	}
+	return "." + key
+}
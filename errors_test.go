@@ -0,0 +1,158 @@
+package fastjson
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSyntaxError_Position(t *testing.T) {
+	input := "{\n\t\"id\": 1,\n\t\"name\" \"x\"\n}"
+
+	var u User
+	err := Unmarshal([]byte(input), &u)
+
+	var synErr *SyntaxError
+	if !errors.As(err, &synErr) {
+		t.Fatalf("expected *SyntaxError, got %v", err)
+	}
+
+	if synErr.Line() != 3 || synErr.Column() != 9 || synErr.Offset != 20 {
+		t.Errorf("unexpected position: line %d, column %d, offset %d", synErr.Line(), synErr.Column(), synErr.Offset)
+	}
+	if synErr.Expected != "':'" || synErr.Found != `'"'` {
+		t.Errorf("unexpected tokens: expected %s, found %s", synErr.Expected, synErr.Found)
+	}
+
+	expected := "\t\"name\" \"x\"\n\t       ^"
+	if synErr.Snippet() != expected {
+		t.Errorf("unexpected snippet:\n%s\nwant:\n%s", synErr.Snippet(), expected)
+	}
+}
+
+func TestSyntaxError_EndOfInput(t *testing.T) {
+	var u User
+	err := Unmarshal([]byte(`{"id": 1`), &u)
+
+	var synErr *SyntaxError
+	if !errors.As(err, &synErr) {
+		t.Fatalf("expected *SyntaxError, got %v", err)
+	}
+	if synErr.Found != "end of input" || synErr.Column() != 9 {
+		t.Errorf("unexpected error: %+v", synErr)
+	}
+}
+
+func TestSyntaxError_LongLine(t *testing.T) {
+	input := `{"padding": "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", "id": x, "more": "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"}`
+
+	var v any
+	err := Unmarshal([]byte(input), &v)
+
+	var synErr *SyntaxError
+	if !errors.As(err, &synErr) {
+		t.Fatalf("expected *SyntaxError, got %v", err)
+	}
+
+	expected := `...aaaaaaaaaaaaaaaaaaaaaaa", "id": x, "more": "bbbbbbbbbbbbbbbbbbbb...` + "\n" +
+		`                                   ^`
+	if synErr.Snippet() != expected {
+		t.Errorf("unexpected snippet:\n%s\nwant:\n%s", synErr.Snippet(), expected)
+	}
+}
+
+func TestUnmarshalTypeError_Path(t *testing.T) {
+	input := `{"status": 200, "data": [{"id": 1}, {"id": 2, "roles": ["admin", 5]}]}`
+
+	var resp APIResponse
+	err := Unmarshal([]byte(input), &resp)
+
+	var typeErr *UnmarshalTypeError
+	if !errors.As(err, &typeErr) {
+		t.Fatalf("expected *UnmarshalTypeError, got %v", err)
+	}
+
+	if typeErr.Path != "$.data[1].roles[1]" {
+		t.Errorf("unexpected path: %s", typeErr.Path)
+	}
+	if typeErr.Struct != "APIResponse" || typeErr.Field != "Data.Roles" {
+		t.Errorf("unexpected field: %s.%s", typeErr.Struct, typeErr.Field)
+	}
+	if typeErr.Value != "number 5" || typeErr.Type != stringType {
+		t.Errorf("unexpected types: %s into %v", typeErr.Value, typeErr.Type)
+	}
+	if typeErr.Offset != 65 {
+		t.Errorf("unexpected offset: %d", typeErr.Offset)
+	}
+}
+
+func TestUnmarshalTypeError_AnonymousStruct(t *testing.T) {
+	input := `{"data": [{"roles": ["admin", 5]}]}`
+
+	var resp struct {
+		Data []APIUser `json:"data"`
+	}
+	err := Unmarshal([]byte(input), &resp)
+
+	var typeErr *UnmarshalTypeError
+	if !errors.As(err, &typeErr) {
+		t.Fatalf("expected *UnmarshalTypeError, got %v", err)
+	}
+	if typeErr.Struct != "APIUser" || typeErr.Field != "Data.Roles" {
+		t.Errorf("unexpected field: %s.%s", typeErr.Struct, typeErr.Field)
+	}
+	if !strings.Contains(err.Error(), "Go struct field APIUser.Data.Roles") {
+		t.Errorf("expected struct context in %q", err)
+	}
+
+	// With no named struct at all, the anonymous type stands in.
+	var flat struct {
+		N int `json:"n"`
+	}
+	err = Unmarshal([]byte(`{"n": "x"}`), &flat)
+	if !errors.As(err, &typeErr) {
+		t.Fatalf("expected *UnmarshalTypeError, got %v", err)
+	}
+	if typeErr.Struct != reflect.TypeOf(flat).String() || typeErr.Field != "N" {
+		t.Errorf("unexpected field: %s.%s", typeErr.Struct, typeErr.Field)
+	}
+}
+
+type intHolder struct {
+	A int64 `json:"a"`
+	B int   `json:"b"`
+}
+
+func TestUnmarshalTypeError_Kinds(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		v     any
+		value string
+		path  string
+	}{
+		{"StringIntoInt", `{"id": "1"}`, &User{}, "string", "$.id"},
+		{"FloatIntoInt", `{"id": 1.5}`, &User{}, "number 1.5", "$.id"},
+		{"OverflowInt32", `[3000000000]`, &[]int32{}, "number 3000000000", "$[0]"},
+		{"PastMaxInt64", `{"a": 9223372036854775808}`, &intHolder{}, "number 9223372036854775808", "$.a"},
+		{"PastMinInt64", `{"a": -9223372036854775809}`, &intHolder{}, "number -9223372036854775809", "$.a"},
+		{"Uint64IntoInt", `{"b": 18446744073709551617}`, &intHolder{}, "number 18446744073709551617", "$.b"},
+		{"ObjectIntoSlice", `{"tags": {}}`, &ComplexUser{}, "object", "$.tags"},
+		{"ArrayIntoStruct", `[]`, &User{}, "array", "$"},
+		{"MapKey", `{"stats": {"odd key": true}}`, &MapUser{}, "bool", `$.stats["odd key"]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Unmarshal([]byte(tt.input), tt.v)
+			var typeErr *UnmarshalTypeError
+			if !errors.As(err, &typeErr) {
+				t.Fatalf("expected *UnmarshalTypeError, got %v", err)
+			}
+			if typeErr.Value != tt.value || typeErr.Path != tt.path {
+				t.Errorf("unexpected error: %v", typeErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package fastjson
+
+import (
+	"reflect"
+	"sync/atomic"
+	"unsafe"
+)
+
+// The functions below take their type from the type parameter instead of
+// an interface value. This spares Marshal's boxing of v and, for
+// non-pointers, the copy it makes to obtain an address. Their codecs are
+// held in genericEncoders and genericDecoders rather than the API's caches.
+
+// genericSlots is the size of the generic codec tables.
+const genericSlots = 256
+
+// genericEntry is a codec compiled by defaultAPI for t at generation gen.
+type genericEntry[F any] struct {
+	t   reflect.Type
+	gen uint64
+	f   F
+}
+
+// The generic codec tables are direct-mapped on the address of the type
+// descriptor, which stands in for the per-instantiation variable Go lacks:
+// a hit costs one atomic load and two compares. Colliding types evict each
+// other and fall back to the API's caches, and an entry from an older
+// generation is recompiled like any cached codec.
+var (
+	genericEncoders [genericSlots]atomic.Pointer[genericEntry[EncoderFunc]]
+	genericDecoders [genericSlots]atomic.Pointer[genericEntry[DecoderFunc]]
+)
+
+// genericSlot returns the table index for t, taken from the data word of
+// the interface, which points at the runtime's type descriptor.
+func genericSlot(t reflect.Type) uintptr {
+	p := (*[2]unsafe.Pointer)(unsafe.Pointer(&t))[1]
+	return uintptr(p) >> 4 % genericSlots
+}
+
+func genericEncoder[T any]() (EncoderFunc, error) {
+	t := reflect.TypeFor[T]()
+	slot := &genericEncoders[genericSlot(t)]
+	gen := defaultAPI.generation()
+	if e := slot.Load(); e != nil && e.t == t && e.gen == gen {
+		return e.f, nil
+	}
+	enc, err := defaultAPI.getEncoder(t)
+	if err != nil {
+		return nil, err
+	}
+	slot.Store(&genericEntry[EncoderFunc]{t: t, gen: gen, f: enc})
+	return enc, nil
+}
+
+func genericDecoder[T any]() (DecoderFunc, error) {
+	t := reflect.TypeFor[T]()
+	slot := &genericDecoders[genericSlot(t)]
+	gen := defaultAPI.generation()
+	if e := slot.Load(); e != nil && e.t == t && e.gen == gen {
+		return e.f, nil
+	}
+	dec, err := defaultAPI.getDecoder(t)
+	if err != nil {
+		return nil, err
+	}
+	slot.Store(&genericEntry[DecoderFunc]{t: t, gen: gen, f: dec})
+	return dec, nil
+}
+
+// MarshalT returns the JSON encoding of *v, or null if v is nil.
+func MarshalT[T any](v *T) ([]byte, error) {
+	if v == nil {
+		return []byte("null"), nil
+	}
+
+	enc, err := genericEncoder[T]()
+	if err != nil {
+		return nil, err
+	}
+
+	w := defaultAPI.getWriter(defaultAPI.encodeOpts)
+	defer PutWriter(w)
+	if err := encodeWith(w, enc, unsafe.Pointer(v)); err != nil {
+		return nil, err
+	}
+
+	result := make([]byte, len(w.Buffer))
+	copy(result, w.Buffer)
+	return result, nil
+}
+
+// AppendT appends the JSON encoding of *v, or null if v is nil, to dst and
+// returns the extended buffer.
+func AppendT[T any](dst []byte, v *T) ([]byte, error) {
+	if v == nil {
+		return append(dst, "null"...), nil
+	}
+	enc, err := genericEncoder[T]()
+	if err != nil {
+		return nil, err
+	}
+	return defaultAPI.appendWith(dst, enc, unsafe.Pointer(v), defaultAPI.encodeOpts)
+}
+
+// UnmarshalT parses data into a new value of type T.
+func UnmarshalT[T any](data []byte) (T, error) {
+	var v T
+	dec, err := genericDecoder[T]()
+	if err != nil {
+		return v, err
+	}
+	err = defaultAPI.decodeWith(data, dec, unsafe.Pointer(&v), defaultAPI.decodeOpts)
+	return v, err
+}
@@ -0,0 +1,90 @@
+package fastjson
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGeneric_RoundTrip(t *testing.T) {
+	in := ComplexUser{User: User{ID: 1, Name: "a"}, Tags: []string{"x"}, Scores: []int{3}}
+
+	data, err := MarshalT(&in)
+	if err != nil {
+		t.Fatalf("MarshalT failed: %v", err)
+	}
+	expected, _ := Marshal(in)
+	if string(data) != string(expected) {
+		t.Errorf("Expected %s, got %s", expected, data)
+	}
+
+	out, err := UnmarshalT[ComplexUser](data)
+	if err != nil {
+		t.Fatalf("UnmarshalT failed: %v", err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("round trip mismatch: %+v", out)
+	}
+
+	buf, err := AppendT([]byte("x="), &in)
+	if err != nil {
+		t.Fatalf("AppendT failed: %v", err)
+	}
+	if string(buf) != "x="+string(expected) {
+		t.Errorf("unexpected output %s", buf)
+	}
+
+	if data, _ := MarshalT[User](nil); string(data) != "null" {
+		t.Errorf("expected null, got %s", data)
+	}
+	if _, err := UnmarshalT[User]([]byte(`[1]`)); err == nil {
+		t.Error("expected type error")
+	}
+}
+
+func TestGeneric_AppendTAllocs(t *testing.T) {
+	u := User{ID: 1, Name: "a"}
+	buf := make([]byte, 0, 256)
+	allocs := testing.AllocsPerRun(100, func() {
+		buf, _ = AppendT(buf[:0], &u)
+	})
+	if allocs != 0 {
+		t.Errorf("AppendT allocated %v times per run, want 0", allocs)
+	}
+}
+
+func BenchmarkMarshalT(b *testing.B) {
+	u := User{ID: 1, Name: "Gemini", IsActive: true, Balance: 99.5}
+	b.Run("Marshal", func(b *testing.B) {
+		for b.Loop() {
+			if _, err := Marshal(u); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("MarshalT", func(b *testing.B) {
+		for b.Loop() {
+			if _, err := MarshalT(&u); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("Codec.Append", func(b *testing.B) {
+		c := CodecFor[User]()
+		buf := make([]byte, 0, 256)
+		for b.Loop() {
+			var err error
+			if buf, err = c.Append(buf[:0], &u); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("AppendT", func(b *testing.B) {
+		buf := make([]byte, 0, 256)
+		for b.Loop() {
+			var err error
+			if buf, err = AppendT(buf[:0], &u); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -77,6 +77,42 @@ func TestUnmarshal_Map(t *testing.T) {
 	}
 }
 
+func TestUnmarshal_InterfaceHoldingPointer(t *testing.T) {
+	u := &User{Name: "kept"}
+	g := Generic{Data: u}
+	if err := Unmarshal([]byte(`{"data": {"id": 7}}`), &g); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if g.Data != u {
+		t.Fatalf("expected the held pointer to be reused, got %#v", g.Data)
+	}
+	if u.ID != 7 || u.Name != "kept" {
+		t.Errorf("unexpected value %+v", u)
+	}
+
+	// Top-level interfaces behave the same way.
+	var v any = u
+	if err := Unmarshal([]byte(`{"name": "x"}`), &v); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if v != any(u) || u.Name != "x" {
+		t.Errorf("unexpected value %#v", v)
+	}
+
+	// Non-pointers are replaced, and null clears the interface.
+	g = Generic{Data: User{}}
+	if err := Unmarshal([]byte(`{"data": {"id": 7}}`), &g); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if _, ok := g.Data.(map[string]any); !ok {
+		t.Errorf("expected map, got %#v", g.Data)
+	}
+	g = Generic{Data: u}
+	if err := Unmarshal([]byte(`{"data": null}`), &g); err != nil || g.Data != nil {
+		t.Errorf("expected nil, got %#v (err %v)", g.Data, err)
+	}
+}
+
 func BenchmarkUnmarshal_Slice(b *testing.B) {
 	jsonStr := []byte(`{"tags": ["one", "two", "three", "four", "five", "six", "seven", "eight", "nine", "ten"]}`)
 	b.Run("FastJSON", func(b *testing.B) {
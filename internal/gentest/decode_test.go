@@ -0,0 +1,140 @@
+package gentest
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/HeartBeat1608/fastjson"
+)
+
+// TestDecodeLikeReflective feeds the generated decoders input that the
+// samples of the generated test never produce: aliases, duplicate and
+// unknown keys, missing required fields and type errors.
+func TestDecodeLikeReflective(t *testing.T) {
+	inputs := []string{
+		`{"id":1,"login":"ann","user":"bob"}`,
+		`{"id":1,"username":"ann","username":"bob"}`,
+		`{"id":1,"extra":1,"extra":2}`,
+		`{"id":1,"nickname":"ann"}`,
+		`{"username":"ann"}`,
+		`{"id":"one","balance":7,"limits":[1,"2"]}`,
+		`{"id":1,"balance":"2.5","active":false,"timeout":5}`,
+		`{"id":1,"priority":"5"}`,
+		`{"id":1,"priority":5}`,
+		`{"id":1,}`,
+		`{"id":1 "role":"admin"}`,
+		`[]`,
+	}
+	orders := []string{
+		`{"Number":1,"Customer":{"id":2,"login":"ann"},"Lines":[{"SKU":"a","Quantity":"x"}]}`,
+		`{"Referrer":null,"Customer":{"role":"admin"},"Extra":{"a":[1]}}`,
+		`{"Lines":[{"SKU":"a"},{"SKU":"b","SKU":"c"}],"Notes":{"k":1}}`,
+	}
+
+	configs := map[string]fastjson.DecodeOptions{
+		"default":        {},
+		"strict":         {Strict: true},
+		"unknown":        {DisallowUnknownFields: true},
+		"first-wins":     {DuplicateKeys: fastjson.DuplicateKeyFirstWins},
+		"reject":         {DuplicateKeys: fastjson.DuplicateKeyReject},
+		"collect-errors": {CollectErrors: true},
+		"max-keys":       {MaxObjectKeys: 2},
+	}
+	for name, opts := range configs {
+		cfg := fastjson.Config{NamingStrategy: fastjson.SnakeCase, Decode: opts}
+		gen := cfg.Freeze()
+		cfg.IgnoreMarshalers = true
+		ref := cfg.Freeze()
+
+		for _, in := range inputs {
+			checkDecode[Account](t, name, ref, gen, in)
+		}
+		for _, in := range orders {
+			checkDecode[Order](t, name, ref, gen, in)
+		}
+	}
+}
+
+func checkDecode[T any](t *testing.T, config string, ref, gen *fastjson.API, in string) {
+	t.Helper()
+	var want, got T
+	wantErr := ref.Unmarshal([]byte(in), &want)
+	gotErr := gen.Unmarshal([]byte(in), &got)
+	if !sameErrorFastJSONGen(gotErr, wantErr) || !reflect.DeepEqual(got, want) {
+		t.Errorf("%s: Unmarshal(%s) = %+v, %v; want %+v, %v", config, in, got, gotErr, want, wantErr)
+	}
+}
+
+// TestEncodeEscapedKeys checks that generated methods escape keys under
+// the same options as the compiled encoders.
+func TestEncodeEscapedKeys(t *testing.T) {
+	line := &Line{SKU: "a", Unit: "kg"}
+	for _, opts := range []fastjson.EncodeOptions{{}, {EscapeHTML: true}, {ASCIIOnly: true}, {Indent: "  "}} {
+		cfg := fastjson.Config{NamingStrategy: fastjson.SnakeCase, Encode: opts}
+		gen := cfg.Freeze()
+		cfg.IgnoreMarshalers = true
+		ref := cfg.Freeze()
+
+		want, wantErr := ref.Marshal(line)
+		got, gotErr := gen.Marshal(line)
+		if gotErr != nil || wantErr != nil || string(got) != string(want) {
+			t.Errorf("%+v: Marshal = %s, %v; want %s, %v", opts, got, gotErr, want, wantErr)
+		}
+	}
+}
+
+// TestDefaultThroughAPI checks that defaults left to the compiled codecs
+// are decoded with the codecs registered on the API doing the decoding.
+func TestDefaultThroughAPI(t *testing.T) {
+	cfg := fastjson.Config{NamingStrategy: fastjson.SnakeCase}
+	gen := cfg.Freeze()
+	cfg.IgnoreMarshalers = true
+	ref := cfg.Freeze()
+
+	upper := func(it *fastjson.Iterator, p unsafe.Pointer) error {
+		s, err := it.ReadString()
+		*(*Tier)(p) = Tier(strings.ToUpper(s))
+		return err
+	}
+	for _, api := range []*fastjson.API{gen, ref} {
+		api.RegisterTypeDecoder(reflect.TypeFor[Tier](), upper)
+	}
+
+	checkDecode[Account](t, "registered", ref, gen, `{"id":1}`)
+	var a Account
+	if err := gen.Unmarshal([]byte(`{"id":1}`), &a); err != nil || a.Tier != "BASIC" {
+		t.Errorf("Unmarshal = %q, %v; want the registered decoder's BASIC", a.Tier, err)
+	}
+}
+
+// lineItem is a union with a generated variant, which is given the
+// discriminator along with the rest of the object.
+type lineItem interface{}
+
+func TestUnionVariant(t *testing.T) {
+	cfg := fastjson.Config{NamingStrategy: fastjson.SnakeCase, Decode: fastjson.DecodeOptions{DisallowUnknownFields: true}}
+	gen := cfg.Freeze()
+	cfg.IgnoreMarshalers = true
+	ref := cfg.Freeze()
+	for _, api := range []*fastjson.API{gen, ref} {
+		err := api.RegisterUnion(reflect.TypeFor[lineItem](), "kind", map[string]reflect.Type{"line": reflect.TypeFor[*Line]()})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	in := []lineItem{&Line{SKU: "a", Quantity: 2}}
+	data, err := gen.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDecode[[]lineItem](t, "union", ref, gen, string(data))
+	checkDecode[[]lineItem](t, "union", ref, gen, `[{"SKU":"b","kind":"line","Other":1}]`)
+
+	var out []lineItem
+	if err := gen.Unmarshal(data, &out); err != nil || !reflect.DeepEqual(out, in) {
+		t.Errorf("Unmarshal(%s) = %+v, %v; want %+v", data, out, err, in)
+	}
+}
@@ -0,0 +1,52 @@
+// Package gentest holds types with methods written by cmd/fastjson-gen. Its
+// generated test checks them against the reflective codecs, and the
+// generator's own test checks that regenerating them changes nothing.
+package gentest
+
+import "time"
+
+//go:generate go run ../../cmd/fastjson-gen -naming snake -output types_fastjson.go -test .
+
+// Account covers the struct tags the generator honors.
+type Account struct {
+	ID       int64         `fastjson:"id,required"`
+	Username string        `fastjson:",alias=login,alias=user"`
+	Email    string        `json:"email_address"`
+	Balance  float64       `fastjson:",format=string"`
+	Active   bool          `fastjson:",default=true"`
+	Role     string        `fastjson:",default=member"`
+	Timeout  time.Duration `fastjson:",default=30s"`
+	Limits   []int         `fastjson:",default=[10,20]"`
+	Priority int           `fastjson:",format=string,default=3"`
+	Tier     Tier          `fastjson:",default=basic"`
+	Password string        `json:"-"`
+	retries  int32
+}
+
+// Tier is a named type the generated code leaves to the compiled codecs,
+// including for its default.
+type Tier string
+
+// Order nests generated types inline, by pointer and in slices, next to
+// fields left to the compiled codecs.
+type Order struct {
+	Number   int
+	Customer Account
+	Referrer *Account
+	Lines    []Line
+	Tags     []string
+	Notes    map[string]string
+	Extra    any
+	Total    float64 `fastjson:"total_amount"`
+}
+
+// Line is an order line.
+type Line struct {
+	SKU      string
+	Quantity int32
+	Price    float64
+	Unit     string `json:"unit<€>"`
+}
+
+// Empty has no fields.
+type Empty struct{}
@@ -0,0 +1,448 @@
+// Code generated by fastjson-gen. DO NOT EDIT.
+
+package gentest
+
+import (
+	"reflect"
+
+	"github.com/HeartBeat1608/fastjson"
+)
+
+// MarshalFastJSON implements fastjson.Marshaler.
+func (x *Account) MarshalFastJSON(w *fastjson.Writer) error {
+	w.ObjectStart()
+	w.ObjectKey(`"id"`, true)
+	w.WriteInt64(x.ID)
+	w.ObjectKey(`"username"`, false)
+	w.WriteStringEscaped(x.Username)
+	w.ObjectKey(`"email_address"`, false)
+	w.WriteStringEscaped(x.Email)
+	w.ObjectKey(`"balance"`, false)
+	w.WriteByte('"')
+	if err := w.EncodeFloat64(x.Balance); err != nil {
+		return err
+	}
+	w.WriteByte('"')
+	w.ObjectKey(`"active"`, false)
+	w.WriteBool(x.Active)
+	w.ObjectKey(`"role"`, false)
+	w.WriteStringEscaped(x.Role)
+	w.ObjectKey(`"timeout"`, false)
+	if err := w.Encode(&x.Timeout); err != nil {
+		return err
+	}
+	w.ObjectKey(`"limits"`, false)
+	if x.Limits == nil {
+		w.WriteNull()
+	} else if len(x.Limits) == 0 {
+		w.WriteString("[]")
+	} else {
+		w.ArrayStart()
+		for i := range x.Limits {
+			w.ArrayElem(i == 0)
+			w.WriteInt64(int64(x.Limits[i]))
+		}
+		w.ArrayEnd()
+	}
+	w.ObjectKey(`"priority"`, false)
+	w.WriteByte('"')
+	w.WriteInt64(int64(x.Priority))
+	w.WriteByte('"')
+	w.ObjectKey(`"tier"`, false)
+	if err := w.Encode(&x.Tier); err != nil {
+		return err
+	}
+	w.ObjectKey(`"retries"`, false)
+	w.WriteInt64(int64(x.retries))
+	w.ObjectEnd()
+	return nil
+}
+
+// UnmarshalFastJSON implements fastjson.Unmarshaler.
+func (x *Account) UnmarshalFastJSON(it *fastjson.Iterator) error {
+	var r fastjson.StructReader
+	if err := r.Begin(it, reflect.TypeFor[Account](), 12, true); err != nil {
+		return err
+	}
+	for {
+		key, ok, err := r.Next()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+		switch key {
+		case "id":
+			if ok, err := r.Field(0); err != nil {
+				return err
+			} else if ok {
+				if err := r.Done(it.DecodeInt64(&x.ID), "ID"); err != nil {
+					return err
+				}
+			}
+		case "username", "login", "user":
+			if ok, err := r.Field(1); err != nil {
+				return err
+			} else if ok {
+				if err := r.Done(it.DecodeString(&x.Username), "Username"); err != nil {
+					return err
+				}
+			}
+		case "email_address":
+			if ok, err := r.Field(2); err != nil {
+				return err
+			} else if ok {
+				if err := r.Done(it.DecodeString(&x.Email), "Email"); err != nil {
+					return err
+				}
+			}
+		case "balance":
+			if ok, err := r.Field(3); err != nil {
+				return err
+			} else if ok {
+				if err := r.Done(it.DecodeQuoted(&x.Balance), "Balance"); err != nil {
+					return err
+				}
+			}
+		case "active":
+			if ok, err := r.Field(4); err != nil {
+				return err
+			} else if ok {
+				if err := r.Done(it.DecodeBool(&x.Active), "Active"); err != nil {
+					return err
+				}
+			}
+		case "role":
+			if ok, err := r.Field(5); err != nil {
+				return err
+			} else if ok {
+				if err := r.Done(it.DecodeString(&x.Role), "Role"); err != nil {
+					return err
+				}
+			}
+		case "timeout":
+			if ok, err := r.Field(6); err != nil {
+				return err
+			} else if ok {
+				if err := r.Done(it.Decode(&x.Timeout), "Timeout"); err != nil {
+					return err
+				}
+			}
+		case "limits":
+			if ok, err := r.Field(7); err != nil {
+				return err
+			} else if ok {
+				if err := r.Done(it.Decode(&x.Limits), "Limits"); err != nil {
+					return err
+				}
+			}
+		case "priority":
+			if ok, err := r.Field(8); err != nil {
+				return err
+			} else if ok {
+				if err := r.Done(it.DecodeQuoted(&x.Priority), "Priority"); err != nil {
+					return err
+				}
+			}
+		case "tier":
+			if ok, err := r.Field(9); err != nil {
+				return err
+			} else if ok {
+				if err := r.Done(it.Decode(&x.Tier), "Tier"); err != nil {
+					return err
+				}
+			}
+		case "retries":
+			if ok, err := r.Field(11); err != nil {
+				return err
+			} else if ok {
+				if err := r.Done(it.DecodeInt32(&x.retries), "retries"); err != nil {
+					return err
+				}
+			}
+		default:
+			if err := r.Unknown(); err != nil {
+				return err
+			}
+		}
+	}
+	if !r.Seen(0) {
+		return r.Missing("id", "ID")
+	}
+	if !r.Seen(4) {
+		x.Active = true
+	}
+	if !r.Seen(5) {
+		x.Role = "member"
+	}
+	if !r.Seen(6) {
+		if err := r.Default(&x.Timeout, "30000000000", "Timeout"); err != nil {
+			return err
+		}
+	}
+	if !r.Seen(7) {
+		if err := r.Default(&x.Limits, "[10,20]", "Limits"); err != nil {
+			return err
+		}
+	}
+	if !r.Seen(8) {
+		x.Priority = 3
+	}
+	if !r.Seen(9) {
+		if err := r.Default(&x.Tier, "\"basic\"", "Tier"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MarshalFastJSON implements fastjson.Marshaler.
+func (x *Empty) MarshalFastJSON(w *fastjson.Writer) error {
+	w.WriteString("{}")
+	return nil
+}
+
+// UnmarshalFastJSON implements fastjson.Unmarshaler.
+func (x *Empty) UnmarshalFastJSON(it *fastjson.Iterator) error {
+	var r fastjson.StructReader
+	if err := r.Begin(it, reflect.TypeFor[Empty](), 0, false); err != nil {
+		return err
+	}
+	for {
+		key, ok, err := r.Next()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+		switch key {
+		default:
+			if err := r.Unknown(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// MarshalFastJSON implements fastjson.Marshaler.
+func (x *Line) MarshalFastJSON(w *fastjson.Writer) error {
+	w.ObjectStart()
+	w.ObjectKey(`"sku"`, true)
+	w.WriteStringEscaped(x.SKU)
+	w.ObjectKey(`"quantity"`, false)
+	w.WriteInt64(int64(x.Quantity))
+	w.ObjectKey(`"price"`, false)
+	if err := w.EncodeFloat64(x.Price); err != nil {
+		return err
+	}
+	w.ObjectKeyEscaped("unit<€>", false)
+	w.WriteStringEscaped(x.Unit)
+	w.ObjectEnd()
+	return nil
+}
+
+// UnmarshalFastJSON implements fastjson.Unmarshaler.
+func (x *Line) UnmarshalFastJSON(it *fastjson.Iterator) error {
+	var r fastjson.StructReader
+	if err := r.Begin(it, reflect.TypeFor[Line](), 4, false); err != nil {
+		return err
+	}
+	for {
+		key, ok, err := r.Next()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+		switch key {
+		case "sku":
+			if ok, err := r.Field(0); err != nil {
+				return err
+			} else if ok {
+				if err := r.Done(it.DecodeString(&x.SKU), "SKU"); err != nil {
+					return err
+				}
+			}
+		case "quantity":
+			if ok, err := r.Field(1); err != nil {
+				return err
+			} else if ok {
+				if err := r.Done(it.DecodeInt32(&x.Quantity), "Quantity"); err != nil {
+					return err
+				}
+			}
+		case "price":
+			if ok, err := r.Field(2); err != nil {
+				return err
+			} else if ok {
+				if err := r.Done(it.DecodeFloat64(&x.Price), "Price"); err != nil {
+					return err
+				}
+			}
+		case "unit<€>":
+			if ok, err := r.Field(3); err != nil {
+				return err
+			} else if ok {
+				if err := r.Done(it.DecodeString(&x.Unit), "Unit"); err != nil {
+					return err
+				}
+			}
+		default:
+			if err := r.Unknown(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// MarshalFastJSON implements fastjson.Marshaler.
+func (x *Order) MarshalFastJSON(w *fastjson.Writer) error {
+	w.ObjectStart()
+	w.ObjectKey(`"number"`, true)
+	w.WriteInt64(int64(x.Number))
+	w.ObjectKey(`"customer"`, false)
+	if err := x.Customer.MarshalFastJSON(w); err != nil {
+		return err
+	}
+	w.ObjectKey(`"referrer"`, false)
+	if x.Referrer == nil {
+		w.WriteNull()
+	} else if err := x.Referrer.MarshalFastJSON(w); err != nil {
+		return err
+	}
+	w.ObjectKey(`"lines"`, false)
+	if x.Lines == nil {
+		w.WriteNull()
+	} else if len(x.Lines) == 0 {
+		w.WriteString("[]")
+	} else {
+		w.ArrayStart()
+		for i := range x.Lines {
+			w.ArrayElem(i == 0)
+			if err := x.Lines[i].MarshalFastJSON(w); err != nil {
+				return err
+			}
+		}
+		w.ArrayEnd()
+	}
+	w.ObjectKey(`"tags"`, false)
+	if x.Tags == nil {
+		w.WriteNull()
+	} else if len(x.Tags) == 0 {
+		w.WriteString("[]")
+	} else {
+		w.ArrayStart()
+		for i := range x.Tags {
+			w.ArrayElem(i == 0)
+			w.WriteStringEscaped(x.Tags[i])
+		}
+		w.ArrayEnd()
+	}
+	w.ObjectKey(`"notes"`, false)
+	if err := w.Encode(&x.Notes); err != nil {
+		return err
+	}
+	w.ObjectKey(`"extra"`, false)
+	if err := w.Encode(&x.Extra); err != nil {
+		return err
+	}
+	w.ObjectKey(`"total_amount"`, false)
+	if err := w.EncodeFloat64(x.Total); err != nil {
+		return err
+	}
+	w.ObjectEnd()
+	return nil
+}
+
+// UnmarshalFastJSON implements fastjson.Unmarshaler.
+func (x *Order) UnmarshalFastJSON(it *fastjson.Iterator) error {
+	var r fastjson.StructReader
+	if err := r.Begin(it, reflect.TypeFor[Order](), 8, false); err != nil {
+		return err
+	}
+	for {
+		key, ok, err := r.Next()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+		switch key {
+		case "number":
+			if ok, err := r.Field(0); err != nil {
+				return err
+			} else if ok {
+				if err := r.Done(it.DecodeInt(&x.Number), "Number"); err != nil {
+					return err
+				}
+			}
+		case "customer":
+			if ok, err := r.Field(1); err != nil {
+				return err
+			} else if ok {
+				if err := r.Done(x.Customer.UnmarshalFastJSON(it), "Customer"); err != nil {
+					return err
+				}
+			}
+		case "referrer":
+			if ok, err := r.Field(2); err != nil {
+				return err
+			} else if ok {
+				if err := r.Done(it.Decode(&x.Referrer), "Referrer"); err != nil {
+					return err
+				}
+			}
+		case "lines":
+			if ok, err := r.Field(3); err != nil {
+				return err
+			} else if ok {
+				if err := r.Done(it.Decode(&x.Lines), "Lines"); err != nil {
+					return err
+				}
+			}
+		case "tags":
+			if ok, err := r.Field(4); err != nil {
+				return err
+			} else if ok {
+				if err := r.Done(it.Decode(&x.Tags), "Tags"); err != nil {
+					return err
+				}
+			}
+		case "notes":
+			if ok, err := r.Field(5); err != nil {
+				return err
+			} else if ok {
+				if err := r.Done(it.Decode(&x.Notes), "Notes"); err != nil {
+					return err
+				}
+			}
+		case "extra":
+			if ok, err := r.Field(6); err != nil {
+				return err
+			} else if ok {
+				if err := r.Done(it.Decode(&x.Extra), "Extra"); err != nil {
+					return err
+				}
+			}
+		case "total_amount":
+			if ok, err := r.Field(7); err != nil {
+				return err
+			} else if ok {
+				if err := r.Done(it.DecodeFloat64(&x.Total), "Total"); err != nil {
+					return err
+				}
+			}
+		default:
+			if err := r.Unknown(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
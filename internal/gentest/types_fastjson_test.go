@@ -0,0 +1,70 @@
+// Code generated by fastjson-gen. DO NOT EDIT.
+
+package gentest
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/HeartBeat1608/fastjson"
+)
+
+// TestFastJSONGenerated checks that the generated methods encode and
+// decode like the reflective codecs.
+func TestFastJSONGenerated(t *testing.T) {
+	cfg := fastjson.Config{NamingStrategy: fastjson.SnakeCase}
+	cfg.Encode.SortMapKeys = true
+	gen := cfg.Freeze()
+	cfg.IgnoreMarshalers = true
+	ref := cfg.Freeze()
+
+	t.Run("Account", func(t *testing.T) {
+		checkFastJSONGen(t, ref, gen, &Account{})
+		checkFastJSONGen(t, ref, gen, &Account{ID: -42, Username: "quote\" slash\\ <html> é\n", Email: "quote\" slash\\ <html> é\n", Balance: 1.5, Active: true, Role: "quote\" slash\\ <html> é\n", Timeout: time.Duration(-42), Limits: []int{-42, -42}, Priority: -42, Tier: Tier("quote\" slash\\ <html> é\n"), Password: "quote\" slash\\ <html> é\n", retries: 7})
+	})
+	t.Run("Empty", func(t *testing.T) {
+		checkFastJSONGen(t, ref, gen, &Empty{})
+	})
+	t.Run("Line", func(t *testing.T) {
+		checkFastJSONGen(t, ref, gen, &Line{})
+		checkFastJSONGen(t, ref, gen, &Line{SKU: "quote\" slash\\ <html> é\n", Quantity: 7, Price: 1.5, Unit: "quote\" slash\\ <html> é\n"})
+	})
+	t.Run("Order", func(t *testing.T) {
+		checkFastJSONGen(t, ref, gen, &Order{})
+		checkFastJSONGen(t, ref, gen, &Order{Number: -42, Customer: Account{ID: -42, Username: "quote\" slash\\ <html> é\n", Email: "quote\" slash\\ <html> é\n", Balance: 1.5, Active: true, Role: "quote\" slash\\ <html> é\n", Timeout: time.Duration(-42), Limits: []int{-42, -42}, Priority: -42, Tier: Tier("quote\" slash\\ <html> é\n"), Password: "quote\" slash\\ <html> é\n", retries: 7}, Referrer: ptrFastJSONGen[Account](Account{ID: -42, Username: "quote\" slash\\ <html> é\n", Email: "quote\" slash\\ <html> é\n", Balance: 1.5, Active: true, Role: "quote\" slash\\ <html> é\n", Timeout: time.Duration(-42), Priority: -42, Tier: Tier("quote\" slash\\ <html> é\n"), Password: "quote\" slash\\ <html> é\n", retries: 7}), Lines: []Line{Line{SKU: "quote\" slash\\ <html> é\n", Quantity: 7, Price: 1.5, Unit: "quote\" slash\\ <html> é\n"}, Line{SKU: "quote\" slash\\ <html> é\n", Quantity: 7, Price: 1.5, Unit: "quote\" slash\\ <html> é\n"}}, Tags: []string{"quote\" slash\\ <html> é\n", "quote\" slash\\ <html> é\n"}, Notes: map[string]string{"key": "quote\" slash\\ <html> é\n"}, Total: 1.5})
+	})
+}
+
+func checkFastJSONGen[T any](t *testing.T, ref, gen *fastjson.API, v *T) {
+	t.Helper()
+	for _, indent := range []string{"", "  "} {
+		want, wantErr := ref.MarshalIndent(v, "", indent)
+		got, gotErr := gen.MarshalIndent(v, "", indent)
+		if !sameErrorFastJSONGen(gotErr, wantErr) || !bytes.Equal(got, want) {
+			t.Fatalf("MarshalIndent(%q) = %s, %v; reflective codecs give %s, %v", indent, got, gotErr, want, wantErr)
+		}
+		if wantErr != nil {
+			continue
+		}
+
+		var fromRef, fromGen T
+		wantErr = ref.Unmarshal(want, &fromRef)
+		gotErr = gen.Unmarshal(want, &fromGen)
+		if !sameErrorFastJSONGen(gotErr, wantErr) || !reflect.DeepEqual(fromGen, fromRef) {
+			t.Fatalf("Unmarshal(%s) = %+v, %v; reflective codecs give %+v, %v", want, fromGen, gotErr, fromRef, wantErr)
+		}
+	}
+}
+
+func sameErrorFastJSONGen(a, b error) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Error() == b.Error()
+}
+
+func ptrFastJSONGen[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,111 @@
+// Package structtag parses the struct tags understood by fastjson. It is
+// shared by the reflective codecs and cmd/fastjson-gen so that both read
+// tags identically.
+package structtag
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Field is the parsed form of a struct field's fastjson or json tag.
+type Field struct {
+	Name string
+	Skip bool
+
+	// Quoted is set by the fastjson tag's `format=string` option, or its
+	// `string` synonym: the value is written inside a JSON string, as in
+	// "42". Like encoding/json, it only applies to numbers and bools. The
+	// json tag's `string` option is ignored, as it always has been here.
+	Quoted bool
+
+	// Required and Aliases can only be set in a fastjson tag. Aliases are
+	// extra keys accepted when decoding; the field is always encoded under
+	// Name.
+	Required bool
+	Aliases  []string
+
+	// Default holds the raw text of a `default=` option. Because default
+	// values may themselves contain commas (e.g. `default=[1,2]`), the
+	// option must come last and extends to the end of the tag.
+	Default    string
+	HasDefault bool
+}
+
+// Parse reads the tags of the field named goName. A fastjson tag takes
+// precedence over the json tag, which is only consulted for the name when
+// the fastjson tag gives none; this lets `fastjson:",required"` sit next to
+// `json:"id"`. Fields whose tags give no name are named by naming, or keep
+// their Go name when naming is nil.
+func Parse(goName string, st reflect.StructTag, naming func(string) string) Field {
+	jsonTag := st.Get("json")
+	tag, fast := st.Lookup("fastjson")
+	if !fast {
+		tag = jsonTag
+	}
+	if tag == "-" {
+		return Field{Skip: true}
+	}
+
+	f := Field{Name: goName}
+	if naming != nil {
+		f.Name = naming(goName)
+	}
+	if tag == "" && !fast {
+		return f
+	}
+
+	name, opts, _ := strings.Cut(tag, ",")
+	if name == "" && fast && jsonTag != "-" {
+		name, _, _ = strings.Cut(jsonTag, ",")
+	}
+	if name != "" {
+		f.Name = name
+	}
+
+	for opts != "" {
+		if def, ok := strings.CutPrefix(opts, "default="); ok {
+			f.Default = def
+			f.HasDefault = true
+			break
+		}
+
+		var opt string
+		opt, opts, _ = strings.Cut(opts, ",")
+		switch {
+		case fast && (opt == "string" || opt == "format=string"):
+			f.Quoted = true
+		case fast && opt == "required":
+			f.Required = true
+		case fast && strings.HasPrefix(opt, "alias="):
+			f.Aliases = append(f.Aliases, opt[len("alias="):])
+		}
+	}
+
+	return f
+}
+
+// DefaultJSON turns the text of a `default=` option into the JSON it
+// stands for. Strings may be given bare, `default=guest`, and durations the
+// way humans write them, `default=30s`.
+func DefaultJSON(def string, isString, isDuration bool) ([]byte, error) {
+	switch {
+	case isDuration && !isIntLiteral(def):
+		d, err := time.ParseDuration(def)
+		if err != nil {
+			return nil, err
+		}
+		return strconv.AppendInt(nil, int64(d), 10), nil
+	case isString && (def == "" || def[0] != '"'):
+		return strconv.AppendQuote(nil, def), nil
+	default:
+		return []byte(def), nil
+	}
+}
+
+func isIntLiteral(s string) bool {
+	_, err := strconv.ParseInt(s, 10, 64)
+	return err == nil
+}
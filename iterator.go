@@ -1,8 +1,9 @@
 package fastjson
 
 import (
-	"fmt"
+	"math"
 	"strconv"
+	"unicode/utf16"
 	"unicode/utf8"
 	"unsafe"
 )
@@ -11,6 +12,18 @@ type Iterator struct {
 	head    int
 	data    []byte
 	dataLen int
+	depth   int
+	opts    DecodeOptions
+	errs    []error // type mismatches recorded under CollectErrors
+	api     *API    // the API decoding from this Iterator; nil means the default
+
+	// discriminator is the key of the union whose variant decodes itself
+	// from the object at discriminatorAt; see StructReader.Begin.
+	discriminator   string
+	discriminatorAt int
+
+	// table drives the string scanning fast path; see SetOptions.
+	table *[256]byte
 }
 
 func NewIterator(data []byte) *Iterator {
@@ -23,12 +36,21 @@ func NewIterator(data []byte) *Iterator {
 
 func (it *Iterator) Reset(data []byte) {
 	it.head = 0
+	it.depth = 0
+	it.errs = it.errs[:0]
+	it.discriminator = ""
 	it.data = data
 	it.dataLen = len(data)
 }
 
 func (it *Iterator) error(msg string) error {
-	return fmt.Errorf("fastjson: %s at offset %d", msg, it.head)
+	return newSyntaxError(it.data, it.head, msg, "")
+}
+
+// expected reports that the token at the current position is not the one
+// described by what.
+func (it *Iterator) expected(what string) error {
+	return newSyntaxError(it.data, it.head, "expected "+what, what)
 }
 
 func bytesToString(b []byte) string {
@@ -44,41 +66,61 @@ func (it *Iterator) skipWhiteSpace() {
 	}
 }
 
+func (it *Iterator) maxDepth() int {
+	if it.opts.MaxDepth > 0 {
+		return it.opts.MaxDepth
+	}
+	return DefaultMaxDepth
+}
+
+// enter records that the iterator has stepped into an object or array.
+// Every successful enter must be paired with a depth decrement once the
+// closing bracket has been consumed.
+func (it *Iterator) enter() error {
+	it.depth++
+	if it.depth > it.maxDepth() {
+		return &MaxDepthError{Max: it.maxDepth(), Offset: it.head}
+	}
+	return nil
+}
+
 // --- Structural Helpers ---
 func (it *Iterator) ReadObjectStart() error {
 	it.skipWhiteSpace()
 	if it.head < it.dataLen && it.data[it.head] == '{' {
 		it.head++
-		return nil
+		return it.enter()
 	}
-	return it.error("expected '{'")
+	return it.expected("'{'")
 }
 
 func (it *Iterator) ReadObjectEnd() error {
 	it.skipWhiteSpace()
 	if it.head < it.dataLen && it.data[it.head] == '}' {
 		it.head++
+		it.depth--
 		return nil
 	}
-	return it.error("expected '}'")
+	return it.expected("'}'")
 }
 
 func (it *Iterator) ReadArrayStart() error {
 	it.skipWhiteSpace()
 	if it.head < it.dataLen && it.data[it.head] == '[' {
 		it.head++
-		return nil
+		return it.enter()
 	}
-	return it.error("expected '['")
+	return it.expected("'['")
 }
 
 func (it *Iterator) ReadArrayEnd() error {
 	it.skipWhiteSpace()
 	if it.head < it.dataLen && it.data[it.head] == ']' {
 		it.head++
+		it.depth--
 		return nil
 	}
-	return it.error("expected ']'")
+	return it.expected("']'")
 }
 
 func (it *Iterator) ReadComma() error {
@@ -87,7 +129,7 @@ func (it *Iterator) ReadComma() error {
 		it.head++
 		return nil
 	}
-	return it.error("expected ','")
+	return it.expected("','")
 }
 
 func (it *Iterator) ReadColon() error {
@@ -96,14 +138,34 @@ func (it *Iterator) ReadColon() error {
 		it.head++
 		return nil
 	}
-	return it.error("expected ':'")
+	return it.expected("':'")
 }
 
 // --- Primitive Parsers ---
 func (it *Iterator) ReadInt64() (int64, error) {
 	it.skipWhiteSpace()
+	start := it.head
+	n, frac, overflow, err := it.scanInt()
+	if err != nil {
+		return 0, err
+	}
+	if frac {
+		return 0, it.error("float found, expected integer")
+	}
+	if overflow {
+		it.head = start
+		return 0, it.typeError(int64Type)
+	}
+	return n, nil
+}
+
+// scanInt reads an integer at it.head. A fraction or exponent is left
+// unconsumed and reported with frac, and a value outside the range of int64
+// is reported with overflow rather than wrapped, so that callers can turn
+// either into a type error without building a syntax error first.
+func (it *Iterator) scanInt() (n int64, frac, overflow bool, err error) {
 	if it.head >= it.dataLen {
-		return 0, it.error("unexpected end of input")
+		return 0, false, false, it.error("unexpected end of input")
 	}
 
 	neg := false
@@ -112,11 +174,16 @@ func (it *Iterator) ReadInt64() (int64, error) {
 		neg = true
 		it.head++
 	case '+':
-		return 0, it.error("leading '+' is not allowed in JSON numbers")
+		return 0, false, false, it.error("leading '+' is not allowed in JSON numbers")
+	}
+
+	limit := uint64(math.MaxInt64)
+	if neg {
+		limit++
 	}
 
 	start := it.head
-	var n int64 = 0
+	var u uint64
 
 	if it.head < it.dataLen && it.data[it.head] == '0' {
 		it.head++
@@ -124,37 +191,45 @@ func (it *Iterator) ReadInt64() (int64, error) {
 		if it.head < it.dataLen {
 			c := it.data[it.head]
 			if c >= '0' && c <= '9' {
-				return 0, it.error("leading zero is not allowed in JSON Numbers")
+				return 0, false, false, it.error("leading zero is not allowed in JSON Numbers")
 			}
 		}
 	} else {
 		for it.head < it.dataLen {
 			c := it.data[it.head]
-			if c >= '0' && c <= '9' {
-				n = n*10 + int64(c-'0')
-				it.head++
-			} else {
+			if c < '0' || c > '9' {
 				break
 			}
+			d := uint64(c - '0')
+			if u > (limit-d)/10 {
+				overflow = true
+			} else if !overflow {
+				u = u*10 + d
+			}
+			it.head++
 		}
 	}
 
 	if it.head == start {
-		return 0, it.error("expected digit")
+		return 0, false, false, it.expected("digit")
+	}
+
+	if max := it.opts.MaxNumberLength; max > 0 && it.head-start > max {
+		return 0, false, false, it.limitError("MaxNumberLength", max)
 	}
 
 	if it.head < it.dataLen {
 		c := it.data[it.head]
 		if c == '.' || c == 'e' || c == 'E' {
-			return 0, it.error("float found, expected integer")
+			return 0, true, overflow, nil
 		}
 	}
 
+	n = int64(u)
 	if neg {
 		n = -n
 	}
-
-	return n, nil
+	return n, false, overflow, nil
 }
 
 func (it *Iterator) ReadFloat64() (float64, error) {
@@ -170,11 +245,31 @@ func (it *Iterator) ReadFloat64() (float64, error) {
 	}
 
 	if it.head == start {
-		return 0, it.error("expected digit")
+		return 0, it.expected("digit")
+	}
+
+	if max := it.opts.MaxNumberLength; max > 0 && it.head-start > max {
+		return 0, it.limitError("MaxNumberLength", max)
+	}
+
+	if it.opts.Strict {
+		// strconv accepts forms JSON does not, such as "1." or "+1".
+		end := it.head
+		it.head = start
+		if err := it.skipNumberStrict(); err != nil {
+			return 0, err
+		}
+		if it.head != end {
+			return 0, it.expected("end of number")
+		}
 	}
 
 	numStr := bytesToString(it.data[start:it.head])
-	return strconv.ParseFloat(numStr, 64)
+	f, err := strconv.ParseFloat(numStr, 64)
+	if err != nil {
+		return 0, newSyntaxError(it.data, start, "invalid number "+strconv.Quote(numStr), "")
+	}
+	return f, nil
 }
 
 func (it *Iterator) ReadBool() (bool, error) {
@@ -188,7 +283,7 @@ func (it *Iterator) ReadBool() (bool, error) {
 			it.head += 4
 			return true, nil
 		}
-		return false, it.error("expected 'true'")
+		return false, it.expected("'true'")
 	}
 
 	if it.data[it.head] == 'f' {
@@ -196,10 +291,10 @@ func (it *Iterator) ReadBool() (bool, error) {
 			it.head += 5
 			return false, nil
 		}
-		return false, it.error("expected 'false'")
+		return false, it.expected("'false'")
 	}
 
-	return false, it.error("expected boolean")
+	return false, it.expected("boolean")
 }
 
 func (it *Iterator) ReadNull() error {
@@ -209,14 +304,14 @@ func (it *Iterator) ReadNull() error {
 	}
 
 	if it.data[it.head] == 'n' {
-		if it.head+3 <= it.dataLen && bytesToString(it.data[it.head:it.head+3]) == "null" {
+		if it.head+4 <= it.dataLen && bytesToString(it.data[it.head:it.head+4]) == "null" {
 			it.head += 4
 			return nil
 		}
-		return it.error("expected 'null'")
+		return it.expected("'null'")
 	}
 
-	return it.error("expected null")
+	return it.expected("null")
 }
 
 func (it *Iterator) ReadString() (string, error) {
@@ -225,15 +320,22 @@ func (it *Iterator) ReadString() (string, error) {
 		return "", it.error("unexpected end of input")
 	}
 	if it.data[it.head] != '"' {
-		return "", it.error("expected start of string '\"'")
+		return "", it.expected("string")
 	}
 
 	it.head++
 	start := it.head
+	table := it.table
+	if table == nil {
+		table = &stringTable
+	}
 	for it.head < it.dataLen {
 		c := it.data[it.head]
-		if stringTable[c] != 0 {
+		if table[c] != 0 {
 			if c == '"' {
+				if max := it.opts.MaxStringLength; max > 0 && it.head-start > max {
+					return "", it.limitError("MaxStringLength", max)
+				}
 				str := bytesToString(it.data[start:it.head])
 				it.head++
 				return str, nil
@@ -244,6 +346,15 @@ func (it *Iterator) ReadString() (string, error) {
 			if c < 0x20 {
 				return "", it.error("control character in string")
 			}
+			// A non-ASCII byte; only flagged when validating UTF-8.
+			if r, size := utf8.DecodeRune(it.data[it.head:]); r != utf8.RuneError || size > 1 {
+				it.head += size
+				continue
+			}
+			if it.opts.InvalidUTF8 == UTF8Reject {
+				return "", it.error("invalid UTF-8 in string")
+			}
+			return it.readStringSlow(start)
 		}
 		it.head++
 	}
@@ -251,9 +362,19 @@ func (it *Iterator) ReadString() (string, error) {
 }
 
 func (it *Iterator) readStringSlow(start int) (string, error) {
-	out := make([]byte, 0, (len(it.data)-start)+16)
+	max := it.opts.MaxStringLength
+	size := (it.dataLen - start) + 16
+	if max > 0 && size > max {
+		size = max
+	}
+	out := make([]byte, 0, size)
 	out = append(out, it.data[start:it.head]...)
-	for it.head < len(it.data) {
+	for it.head < it.dataLen {
+		// Check as the string grows, so that an oversized one fails
+		// before it has all been copied.
+		if max > 0 && len(out) > max {
+			return "", it.limitError("MaxStringLength", max)
+		}
 		c := it.data[it.head]
 		if c == '"' {
 			it.head++
@@ -261,7 +382,7 @@ func (it *Iterator) readStringSlow(start int) (string, error) {
 		}
 		if c == '\\' {
 			it.head++
-			if it.head >= len(it.data) {
+			if it.head >= it.dataLen {
 				return "", it.error("unexpected end of input in escape")
 			}
 			escape := it.data[it.head]
@@ -279,17 +400,20 @@ func (it *Iterator) readStringSlow(start int) (string, error) {
 			case 't':
 				out = append(out, '\t')
 			case 'u':
-				if it.head+4 >= len(it.data) {
+				if it.head+4 >= it.dataLen {
 					return "", it.error("incomplete unicode escape")
 				}
 				r, err := it.decodeUnicode()
 				if err != nil {
 					return "", err
 				}
-				var buf [4]byte
-				n := utf8.EncodeRune(buf[:], r)
-				out = append(out, buf[:n]...)
 				it.head += 4
+				if utf16.IsSurrogate(r) {
+					if r, err = it.readSurrogatePair(r); err != nil {
+						return "", err
+					}
+				}
+				out = utf8.AppendRune(out, r)
 			default:
 				return "", it.error("invalid escape sequence")
 			}
@@ -299,15 +423,53 @@ func (it *Iterator) readStringSlow(start int) (string, error) {
 		if c < 0x20 {
 			return "", it.error("control character in string")
 		}
+		if c >= utf8.RuneSelf && it.opts.InvalidUTF8 != UTF8Allow {
+			r, size := utf8.DecodeRune(it.data[it.head:])
+			if r == utf8.RuneError && size == 1 {
+				if it.opts.InvalidUTF8 == UTF8Reject {
+					return "", it.error("invalid UTF-8 in string")
+				}
+				out = utf8.AppendRune(out, utf8.RuneError)
+				it.head++
+				continue
+			}
+			out = append(out, it.data[it.head:it.head+size]...)
+			it.head += size
+			continue
+		}
 		out = append(out, c)
 		it.head++
 	}
 	return "", it.error("unexpected end of input in string")
 }
 
+// readSurrogatePair completes a UTF-16 surrogate pair whose first half, r,
+// has just been read from a \u escape. An unpaired surrogate becomes U+FFFD,
+// or an error under Strict or UTF8Reject.
+func (it *Iterator) readSurrogatePair(r rune) (rune, error) {
+	// it.head is on the last hex digit of the first escape.
+	next := it.head + 1
+	if r < 0xdc00 && next+5 < it.dataLen && it.data[next] == '\\' && it.data[next+1] == 'u' {
+		it.head = next + 1
+		r2, err := it.decodeUnicode()
+		if err == nil {
+			if pair := utf16.DecodeRune(r, r2); pair != utf8.RuneError {
+				it.head += 4
+				return pair, nil
+			}
+		}
+		it.head = next - 1
+	}
+
+	if it.opts.Strict || it.opts.InvalidUTF8 == UTF8Reject {
+		return 0, newSyntaxError(it.data, it.head-5, "unpaired UTF-16 surrogate in string", "")
+	}
+	return utf8.RuneError, nil
+}
+
 func (it *Iterator) decodeUnicode() (rune, error) {
 	start := it.head + 1
-	if start+4 > len(it.data) {
+	if start+4 > it.dataLen {
 		return 0, it.error("incomplete unicode escape")
 	}
 	var r rune
@@ -336,7 +498,14 @@ func (it *Iterator) char() byte {
 	return it.data[it.head]
 }
 
+// SkipValue consumes the next value without decoding it. By default it only
+// tracks enough structure to find the end of the value; with
+// DecodeOptions.Strict it enforces the full JSON grammar.
 func (it *Iterator) SkipValue() error {
+	if it.opts.Strict {
+		return it.skipStrict()
+	}
+
 	it.skipWhiteSpace()
 
 	if it.head >= it.dataLen {
@@ -357,43 +526,24 @@ func (it *Iterator) SkipValue() error {
 			}
 			it.head++
 		}
-	case '{':
-		it.head++
-		depth := 1
-		for it.head < it.dataLen && depth > 0 {
-			c := it.data[it.head]
-			if c == '{' {
-				depth++
-			} else if c == '}' {
-				depth--
-			} else if c == '"' {
-				it.head++
-				for it.head < it.dataLen {
-					if it.data[it.head] == '"' {
-						break
-					}
-					if it.data[it.head] == '\\' {
-						it.head++
-					}
-					it.head++
-				}
-			}
-			it.head++
-		}
-		if depth > 0 {
-			return it.error("unternimated object")
+	case '{', '[':
+		// Objects and arrays are skipped without recursion; depth counts
+		// both kinds of bracket so the nesting limit still applies.
+		if err := it.enter(); err != nil {
+			return err
 		}
-		return nil
-	case '[':
 		it.head++
 		depth := 1
 		for it.head < it.dataLen && depth > 0 {
-			c := it.data[it.head]
-			if c == '[' {
+			switch it.data[it.head] {
+			case '{', '[':
 				depth++
-			} else if c == ']' {
+				if it.depth+depth-1 > it.maxDepth() {
+					return &MaxDepthError{Max: it.maxDepth(), Offset: it.head}
+				}
+			case '}', ']':
 				depth--
-			} else if c == '"' {
+			case '"':
 				it.head++
 				for it.head < it.dataLen {
 					if it.data[it.head] == '"' {
@@ -407,8 +557,12 @@ func (it *Iterator) SkipValue() error {
 			}
 			it.head++
 		}
+		it.depth--
 
 		if depth > 0 {
+			if c == '{' {
+				return it.error("unternimated object")
+			}
 			return it.error("unternimated array")
 		}
 		return nil
@@ -423,5 +577,5 @@ func (it *Iterator) SkipValue() error {
 		return nil
 	}
 
-	return it.error("expected value")
+	return it.expected("value")
 }
@@ -7,46 +7,96 @@ import (
 
 // Marshal returns the JSON encoding of v.
 func Marshal(v any) ([]byte, error) {
-	if v == nil {
-		return []byte("null"), nil
-	}
+	return defaultAPI.marshal(v, EncodeOptions{})
+}
 
-	w := GetWriter()
+// MarshalIndent is like Marshal but formats the output with one element per
+// line, each beginning with prefix and indented by one copy of indent per
+// level of nesting.
+func MarshalIndent(v any, prefix, indent string) ([]byte, error) {
+	return defaultAPI.marshal(v, EncodeOptions{Prefix: prefix, Indent: indent})
+}
+
+// MarshalWithOptions is like Marshal but applies opts to the output.
+func MarshalWithOptions(v any, opts EncodeOptions) ([]byte, error) {
+	return defaultAPI.marshal(v, opts)
+}
+
+func (a *API) marshal(v any, opts EncodeOptions) ([]byte, error) {
+	w := a.getWriter(opts)
 	defer PutWriter(w)
 
+	if err := a.encode(w, v); err != nil {
+		return nil, err
+	}
+
+	// Copy the result buffer to return ownership to caller
+	// (Since we reuse the Writer in a pool, we can't return w.Buffer directly)
+	result := make([]byte, len(w.Buffer))
+	copy(result, w.Buffer)
+	return result, nil
+}
+
+// encode appends the encoding of v to w.
+func (a *API) encode(w *Writer, v any) error {
+	if v == nil {
+		w.WriteNull()
+		return nil
+	}
+
 	// Reflection is unavoidable at the very top level to unwrap the interface{}
 	rv := reflect.ValueOf(v)
 	t := rv.Type()
 
-	var ptr unsafe.Pointer
-	var enc EncoderFunc
-	var err error
-
 	if t.Kind() == reflect.Pointer {
 		if rv.IsNil() {
-			return []byte("null"), nil
+			w.WriteNull()
+			return nil
 		}
-		enc, err = getEncoder(t.Elem())
-		ptr = unsafe.Pointer(rv.Pointer())
-	} else {
-		enc, err = getEncoder(t)
-
-		newPtr := reflect.New(t)
-		newPtr.Elem().Set(rv)
-		ptr = unsafe.Pointer(newPtr.Pointer())
+		return a.encodeAt(w, t.Elem(), unsafe.Pointer(rv.Pointer()))
 	}
 
+	newPtr := reflect.New(t)
+	newPtr.Elem().Set(rv)
+	return a.encodeAt(w, t, unsafe.Pointer(newPtr.Pointer()))
+}
+
+// encodeAt appends the encoding of the value of type t at p to w.
+func (a *API) encodeAt(w *Writer, t reflect.Type, p unsafe.Pointer) error {
+	enc, err := a.getEncoder(t)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	return encodeWith(w, enc, p)
+}
 
-	if err := enc(w, ptr); err != nil {
-		return nil, err
+func encodeWith(w *Writer, enc EncoderFunc, p unsafe.Pointer) error {
+	if err := enc(w, p); err != nil {
+		return err
 	}
+	return w.Err()
+}
 
-	// Copy the result buffer to return ownership to caller
-	// (Since we reuse the Writer in a pool, we can't return w.Buffer directly)
-	result := make([]byte, len(w.Buffer))
-	copy(result, w.Buffer)
-	return result, nil
+// getWriter returns a pooled Writer set up to encode for a.
+func (a *API) getWriter(opts EncodeOptions) *Writer {
+	w := GetWriter()
+	w.SetOptions(opts)
+	w.api = a
+	return w
+}
+
+// appendWith appends the encoding of the value at p to dst using enc.
+func (a *API) appendWith(dst []byte, enc EncoderFunc, p unsafe.Pointer, opts EncodeOptions) ([]byte, error) {
+	w := a.getWriter(opts)
+	defer PutWriter(w)
+
+	// Encode straight into dst, handing the pooled buffer back afterwards.
+	pooled := w.Buffer
+	w.Buffer = dst
+	err := encodeWith(w, enc, p)
+	dst, w.Buffer = w.Buffer, pooled
+	if err != nil {
+		return nil, err
+	}
+	return dst, nil
 }
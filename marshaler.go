@@ -0,0 +1,304 @@
+package fastjson
+
+import (
+	"fmt"
+	"reflect"
+	"unsafe"
+)
+
+// Marshaler is implemented by types that encode themselves, such as those
+// given MarshalFastJSON methods by cmd/fastjson-gen. The method is looked up
+// on the pointer type and used wherever the type appears, unless a codec is
+// registered for it or the API was configured with IgnoreMarshalers.
+type Marshaler interface {
+	MarshalFastJSON(w *Writer) error
+}
+
+// Unmarshaler is the decoding counterpart of Marshaler. UnmarshalFastJSON
+// must consume exactly one value.
+type Unmarshaler interface {
+	UnmarshalFastJSON(it *Iterator) error
+}
+
+var (
+	marshalerType   = reflect.TypeFor[Marshaler]()
+	unmarshalerType = reflect.TypeFor[Unmarshaler]()
+)
+
+func marshalerEncoder(t reflect.Type) EncoderFunc {
+	return func(w *Writer, p unsafe.Pointer) error {
+		return reflect.NewAt(t, p).Interface().(Marshaler).MarshalFastJSON(w)
+	}
+}
+
+func unmarshalerDecoder(t reflect.Type) DecoderFunc {
+	return func(it *Iterator, p unsafe.Pointer) error {
+		return reflect.NewAt(t, p).Interface().(Unmarshaler).UnmarshalFastJSON(it)
+	}
+}
+
+// Encode appends the encoding of v with the codecs of the API that w is
+// encoding for. It lets MarshalFastJSON methods fall back to the compiled
+// encoders for values they do not encode themselves.
+func (w *Writer) Encode(v any) error {
+	a := w.api
+	if a == nil {
+		a = defaultAPI
+	}
+	return a.encode(w, v)
+}
+
+// Decode decodes the next value into the value pointed to by v with the
+// codecs of the API that it is decoding for; see Writer.Encode.
+func (it *Iterator) Decode(v any) error {
+	t, p, err := it.target(v)
+	if err != nil {
+		return err
+	}
+	dec, err := it.decoderFor(t)
+	if err != nil {
+		return err
+	}
+	return dec(it, p)
+}
+
+// DecodeQuoted is like Decode for a value written inside a JSON string, as
+// for fields tagged `format=string`.
+func (it *Iterator) DecodeQuoted(v any) error {
+	t, p, err := it.target(v)
+	if err != nil {
+		return err
+	}
+	dec, err := it.decoderFor(t)
+	if err != nil {
+		return err
+	}
+	return quotedDecoder(t, dec)(it, p)
+}
+
+func (it *Iterator) target(v any) (reflect.Type, unsafe.Pointer, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return nil, nil, &InvalidUnmarshalError{Type: reflect.TypeOf(v)}
+	}
+	return rv.Type().Elem(), rv.UnsafePointer(), nil
+}
+
+func (it *Iterator) decoderFor(t reflect.Type) (DecoderFunc, error) {
+	a := it.api
+	if a == nil {
+		a = defaultAPI
+	}
+	return a.getDecoder(t)
+}
+
+// StructReader walks the members of a JSON object for UnmarshalFastJSON
+// methods, with the same handling of duplicate keys, unknown fields,
+// collected errors and error paths as the compiled struct decoders. A zero
+// StructReader is ready for Begin; a typical method looks like:
+//
+//	var r fastjson.StructReader
+//	if err := r.Begin(it, reflect.TypeFor[User](), 2, false); err != nil {
+//		return err
+//	}
+//	for {
+//		key, ok, err := r.Next()
+//		if err != nil {
+//			return err
+//		}
+//		if !ok {
+//			break
+//		}
+//		switch key {
+//		case "name":
+//			if ok, err := r.Field(0); err != nil {
+//				return err
+//			} else if ok {
+//				if err := r.Done(it.DecodeString(&u.Name), "Name"); err != nil {
+//					return err
+//				}
+//			}
+//		default:
+//			if err := r.Unknown(); err != nil {
+//				return err
+//			}
+//		}
+//	}
+type StructReader struct {
+	it   *Iterator
+	t    reflect.Type
+	keys int // members read so far
+
+	// discriminator is the key of the union this object is a variant of,
+	// which Unknown accepts even under DisallowUnknownFields.
+	discriminator string
+
+	key   string // current key
+	keyAt int    // offset of the current key
+	mark  int    // len(it.errs) before the current value
+
+	seenBuf    [2]uint64
+	seen       fieldSet
+	firstAtBuf [16]int
+	firstAt    []int          // offset of each field's first key, under DuplicateKeyReject
+	unknown    map[string]int // offsets of unknown keys, under DuplicateKeyReject
+}
+
+// Begin consumes the '{' opening an object to be decoded into struct type
+// t, which has numFields fields. track asks for Seen to be kept, for
+// required fields and defaults.
+func (r *StructReader) Begin(it *Iterator, t reflect.Type, numFields int, track bool) error {
+	*r = StructReader{it: it, t: t}
+	it.skipWhiteSpace()
+	if it.char() != '{' {
+		return it.typeError(t)
+	}
+	if it.discriminator != "" && it.discriminatorAt == it.head {
+		r.discriminator, it.discriminator = it.discriminator, ""
+	}
+	if err := it.ReadObjectStart(); err != nil {
+		return err
+	}
+
+	policy := it.opts.DuplicateKeys
+	if track || policy != DuplicateKeyLastWins {
+		r.seen = newFieldSet(r.seenBuf[:0], numFields)
+		if policy == DuplicateKeyReject {
+			r.firstAt = keyOffsets(r.firstAtBuf[:0], numFields)
+		}
+	}
+	return nil
+}
+
+// Next reads the next key and its colon. ok is false once the object's
+// closing '}' has been consumed.
+func (r *StructReader) Next() (key string, ok bool, err error) {
+	it := r.it
+	if r.keys > 0 {
+		it.skipWhiteSpace()
+		switch it.char() {
+		case ',':
+			it.head++
+			if err := it.rejectTrailingComma('}'); err != nil {
+				return "", false, err
+			}
+		case '}':
+			it.head++
+			it.depth--
+			return "", false, nil
+		default:
+			return "", false, it.expected("',' or '}'")
+		}
+	}
+
+	it.skipWhiteSpace()
+	if it.char() == '}' {
+		it.head++
+		it.depth--
+		return "", false, nil
+	}
+	if max := it.opts.MaxObjectKeys; max > 0 && r.keys >= max {
+		return "", false, it.limitError("MaxObjectKeys", max)
+	}
+	r.keys++
+
+	r.keyAt = it.head
+	if r.key, err = it.ReadString(); err != nil {
+		return "", false, err
+	}
+	if err := it.ReadColon(); err != nil {
+		return "", false, err
+	}
+	it.skipWhiteSpace()
+	return r.key, true, nil
+}
+
+// Field reports whether the current value should be decoded into field i.
+// When an earlier occurrence of the field wins, Field skips the value and
+// returns false.
+func (r *StructReader) Field(i int) (bool, error) {
+	if r.seen != nil {
+		switch {
+		case !r.seen.has(i):
+			r.seen.add(i)
+			if r.firstAt != nil {
+				r.firstAt[i] = r.keyAt
+			}
+		case r.it.opts.DuplicateKeys == DuplicateKeyReject:
+			return false, &DuplicateKeyError{Key: r.key, First: r.firstAt[i], Offset: r.keyAt}
+		case r.it.opts.DuplicateKeys == DuplicateKeyFirstWins:
+			return false, r.it.SkipValue()
+		}
+	}
+	r.mark = len(r.it.errs)
+	return true, nil
+}
+
+// Done takes the result of decoding the current value into the field
+// named goField. Type errors get the field's path, and are kept for later
+// under DecodeOptions.CollectErrors.
+func (r *StructReader) Done(err error, goField string) error {
+	it := r.it
+	if err != nil {
+		if err := it.collect(err); err != nil {
+			return withFieldPath(err, r.t, goField, r.key)
+		}
+	}
+	for _, e := range it.errs[r.mark:] {
+		withFieldPath(e, r.t, goField, r.key)
+	}
+	return nil
+}
+
+// Unknown skips the value of a key that matches no field, or fails under
+// DecodeOptions.DisallowUnknownFields, or when the key is repeated under
+// DuplicateKeyReject. When the object is decoded as a variant of a
+// union, the union's discriminator key is never reported as unknown.
+func (r *StructReader) Unknown() error {
+	it := r.it
+	if it.opts.DisallowUnknownFields && r.key != r.discriminator {
+		return &UnknownFieldError{Key: r.key, Struct: r.t.Name(), Offset: r.keyAt}
+	}
+	if it.opts.DuplicateKeys == DuplicateKeyReject {
+		if r.unknown == nil {
+			r.unknown = make(map[string]int)
+		}
+		if _, err := it.checkDuplicate(r.unknown, r.key, r.keyAt); err != nil {
+			return err
+		}
+	}
+	return it.SkipValue()
+}
+
+// Default decodes raw, the JSON of the `default=` value of the field
+// named goField, into the value pointed to by v. Like the compiled
+// decoders, it uses the codecs of the API the reader decodes for, and
+// none of the options of the input.
+func (r *StructReader) Default(v any, raw, goField string) error {
+	t, p, err := r.it.target(v)
+	if err != nil {
+		return err
+	}
+	dec, err := r.it.decoderFor(t)
+	if err != nil {
+		return err
+	}
+	a := r.it.api
+	if a == nil {
+		a = defaultAPI
+	}
+	if err := a.decodeDefault([]byte(raw), dec, p); err != nil {
+		return fmt.Errorf("fastjson: invalid default %s for field %s: %w", raw, goField, err)
+	}
+	return nil
+}
+
+// Seen reports whether field i was present. It requires Begin's track.
+func (r *StructReader) Seen(i int) bool {
+	return r.seen.has(i)
+}
+
+// Missing returns the error for a required field that was not present.
+func (r *StructReader) Missing(key, goField string) error {
+	return &RequiredFieldError{Key: key, Struct: r.t.Name(), Field: goField, Offset: r.it.head}
+}
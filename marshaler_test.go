@@ -0,0 +1,214 @@
+package fastjson
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// point encodes itself as a two-element array.
+type point struct {
+	X, Y int64
+}
+
+func (p *point) MarshalFastJSON(w *Writer) error {
+	w.ArrayStart()
+	w.ArrayElem(true)
+	w.WriteInt64(p.X)
+	w.ArrayElem(false)
+	w.WriteInt64(p.Y)
+	w.ArrayEnd()
+	return nil
+}
+
+func (p *point) UnmarshalFastJSON(it *Iterator) error {
+	var xy []int64
+	if err := it.Decode(&xy); err != nil {
+		return err
+	}
+	if len(xy) != 2 {
+		return errors.New("point needs two coordinates")
+	}
+	p.X, p.Y = xy[0], xy[1]
+	return nil
+}
+
+type shape struct {
+	Name   string           `json:"name"`
+	Points []point          `json:"points"`
+	Origin *point           `json:"origin"`
+	Named  map[string]point `json:"named"`
+}
+
+func TestMarshaler_UsedWhereverTypeAppears(t *testing.T) {
+	s := shape{
+		Name:   "tri",
+		Points: []point{{1, 2}, {3, 4}},
+		Origin: &point{0, 0},
+		Named:  map[string]point{"a": {5, 6}},
+	}
+	got, err := Marshal(&s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"name":"tri","points":[[1,2],[3,4]],"origin":[0,0],"named":{"a":[5,6]}}`
+	if string(got) != want {
+		t.Errorf("Marshal = %s, want %s", got, want)
+	}
+
+	var back shape
+	if err := Unmarshal(got, &back); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(back, s) {
+		t.Errorf("round trip = %+v, want %+v", back, s)
+	}
+
+	got, err = MarshalIndent(&s.Points, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = "[\n  [\n    1,\n    2\n  ],\n  [\n    3,\n    4\n  ]\n]"
+	if string(got) != want {
+		t.Errorf("MarshalIndent = %q, want %q", got, want)
+	}
+}
+
+func TestMarshaler_IgnoreMarshalers(t *testing.T) {
+	api := Config{IgnoreMarshalers: true}.Freeze()
+	got, err := api.Marshal(&point{1, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != `{"X":1,"Y":2}` {
+		t.Errorf("Marshal = %s, want the reflective encoding", got)
+	}
+
+	var p point
+	if err := api.Unmarshal([]byte(`{"X":3,"Y":4}`), &p); err != nil || p != (point{3, 4}) {
+		t.Errorf("Unmarshal = %+v, %v", p, err)
+	}
+	if !api.Config().IgnoreMarshalers {
+		t.Error("Config() lost IgnoreMarshalers")
+	}
+}
+
+func TestMarshaler_FallbacksUseCallingAPI(t *testing.T) {
+	// A registered codec on the API reaches values the method hands back
+	// to Writer.Encode and Iterator.Decode.
+	api := Config{}.Freeze()
+	api.RegisterTypeEncoder(centsType, encodeCents)
+	api.RegisterTypeDecoder(centsType, decodeCents)
+
+	got, err := api.Marshal(&walletHolder{W: wallet{Balance: 1234}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != `{"W":{"balance":"12.34"}}` {
+		t.Errorf("Marshal = %s", got)
+	}
+
+	var h walletHolder
+	if err := api.Unmarshal(got, &h); err != nil || h.W.Balance != 1234 {
+		t.Errorf("Unmarshal = %+v, %v", h, err)
+	}
+}
+
+type walletHolder struct {
+	W wallet
+}
+
+type wallet struct {
+	Balance cents
+}
+
+func (v *wallet) MarshalFastJSON(w *Writer) error {
+	w.ObjectStart()
+	w.ObjectKey(`"balance"`, true)
+	if err := w.Encode(&v.Balance); err != nil {
+		return err
+	}
+	w.ObjectEnd()
+	return nil
+}
+
+func (v *wallet) UnmarshalFastJSON(it *Iterator) error {
+	var r StructReader
+	if err := r.Begin(it, reflect.TypeFor[wallet](), 1, true); err != nil {
+		return err
+	}
+	for {
+		key, ok, err := r.Next()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+		switch key {
+		case "balance":
+			if ok, err := r.Field(0); err != nil {
+				return err
+			} else if ok {
+				if err := r.Done(it.Decode(&v.Balance), "Balance"); err != nil {
+					return err
+				}
+			}
+		default:
+			if err := r.Unknown(); err != nil {
+				return err
+			}
+		}
+	}
+	if !r.Seen(0) {
+		return r.Missing("balance", "Balance")
+	}
+	return nil
+}
+
+func TestStructReader_Errors(t *testing.T) {
+	var h walletHolder
+	tests := []struct {
+		in   string
+		opts DecodeOptions
+		want string
+	}{
+		{`{"W":{}}`, DecodeOptions{}, `missing required key "balance"`},
+		{`{"W":{"balance":1,"balance":2}}`, DecodeOptions{DuplicateKeys: DuplicateKeyReject}, `duplicate key "balance"`},
+		{`{"W":{"balance":1,"x":2}}`, DecodeOptions{DisallowUnknownFields: true}, `unknown field "x"`},
+		{`{"W":{"balance":1,"x":2,"x":3}}`, DecodeOptions{DuplicateKeys: DuplicateKeyReject}, `duplicate key "x"`},
+		{`{"W":{"balance":true}}`, DecodeOptions{}, `Go struct field walletHolder.W.Balance of type int64 at $.W.balance`},
+		{`{"W":{"balance":1,}}`, DecodeOptions{Strict: true}, `expected value`},
+		{`{"W":{"balance":1 "x":2}}`, DecodeOptions{}, `expected ',' or '}'`},
+		{`{"W":[]}`, DecodeOptions{}, `cannot unmarshal array into Go struct field walletHolder.W of type fastjson.wallet`},
+	}
+	for _, tt := range tests {
+		err := UnmarshalWithOptions([]byte(tt.in), &h, tt.opts)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("UnmarshalWithOptions(%s) = %v, want error containing %q", tt.in, err, tt.want)
+		}
+	}
+}
+
+func TestStructReader_FirstWins(t *testing.T) {
+	var h walletHolder
+	in := `{"W":{"balance":1,"balance":2}}`
+	if err := UnmarshalWithOptions([]byte(in), &h, DecodeOptions{DuplicateKeys: DuplicateKeyFirstWins}); err != nil {
+		t.Fatal(err)
+	}
+	if h.W.Balance != 1 {
+		t.Errorf("Balance = %d, want the first occurrence", h.W.Balance)
+	}
+}
+
+func TestStructReader_RejectAllocs(t *testing.T) {
+	var h walletHolder
+	in := []byte(`{"W":{"balance":1}}`)
+	opts := DecodeOptions{DuplicateKeys: DuplicateKeyReject}
+	want := testing.AllocsPerRun(100, func() { _ = Unmarshal(in, &h) })
+	got := testing.AllocsPerRun(100, func() { _ = UnmarshalWithOptions(in, &h, opts) })
+	if got != want {
+		t.Errorf("StructReader allocated %v times per run under Reject, want %v", got, want)
+	}
+}
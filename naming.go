@@ -0,0 +1,65 @@
+package fastjson
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// NamingStrategy derives the JSON key of a struct field that has no name in
+// its tag from the field's Go name. It runs once per field when a codec is
+// compiled, never while encoding or decoding.
+type NamingStrategy func(goName string) string
+
+// SnakeCase maps UserID to user_id and HTTPServer to http_server.
+func SnakeCase(name string) string {
+	return strings.ToLower(strings.Join(splitWords(name), "_"))
+}
+
+// KebabCase maps UserID to user-id and HTTPServer to http-server.
+func KebabCase(name string) string {
+	return strings.ToLower(strings.Join(splitWords(name), "-"))
+}
+
+// CamelCase lowers the first word of name and joins the words, mapping
+// UserID to userID, HTTPServer to httpServer and Created_At to createdAt.
+func CamelCase(name string) string {
+	words := splitWords(name)
+	if len(words) == 0 {
+		return name
+	}
+	words[0] = strings.ToLower(words[0])
+	return strings.Join(words, "")
+}
+
+// splitWords splits a Go identifier before each upper-case letter that
+// starts a word, keeping acronyms together: "HTTPServerID2" becomes
+// ["HTTP", "Server", "ID2"]. Underscores also separate words.
+func splitWords(name string) []string {
+	var words []string
+	start := 0
+	var prev rune
+	for i, r := range name {
+		if r == '_' {
+			if i > start {
+				words = append(words, name[start:i])
+			}
+			start = i + 1
+			prev = r
+			continue
+		}
+
+		if i > start && unicode.IsUpper(r) {
+			next, _ := utf8.DecodeRuneInString(name[i+utf8.RuneLen(r):])
+			if !unicode.IsUpper(prev) || unicode.IsLower(next) {
+				words = append(words, name[start:i])
+				start = i
+			}
+		}
+		prev = r
+	}
+	if start < len(name) {
+		words = append(words, name[start:])
+	}
+	return words
+}
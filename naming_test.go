@@ -0,0 +1,74 @@
+package fastjson
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNamingStrategies(t *testing.T) {
+	tests := []struct {
+		name                string
+		snake, kebab, camel string
+	}{
+		{"Name", "name", "name", "name"},
+		{"UserID", "user_id", "user-id", "userID"},
+		{"HTTPServer", "http_server", "http-server", "httpServer"},
+		{"APIKey2", "api_key2", "api-key2", "apiKey2"},
+		{"ID", "id", "id", "id"},
+		{"IsActive", "is_active", "is-active", "isActive"},
+		{"Created_At", "created_at", "created-at", "createdAt"},
+		{"ID_Value", "id_value", "id-value", "idValue"},
+		{"_Foo", "foo", "foo", "foo"},
+		{"_Foo_Bar", "foo_bar", "foo-bar", "fooBar"},
+	}
+
+	for _, tt := range tests {
+		if got := SnakeCase(tt.name); got != tt.snake {
+			t.Errorf("SnakeCase(%q) = %q, want %q", tt.name, got, tt.snake)
+		}
+		if got := KebabCase(tt.name); got != tt.kebab {
+			t.Errorf("KebabCase(%q) = %q, want %q", tt.name, got, tt.kebab)
+		}
+		if got := CamelCase(tt.name); got != tt.camel {
+			t.Errorf("CamelCase(%q) = %q, want %q", tt.name, got, tt.camel)
+		}
+	}
+}
+
+func TestConfig_NamingStrategy(t *testing.T) {
+	type Account struct {
+		AccountID   int
+		DisplayName string `json:"name"` // explicit names are kept
+		IsVerified  bool
+	}
+
+	api := Config{NamingStrategy: SnakeCase}.Freeze()
+	in := Account{AccountID: 7, DisplayName: "x", IsVerified: true}
+	got, err := api.Marshal(in)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if string(got) != `{"account_id":7,"name":"x","is_verified":true}` {
+		t.Errorf("unexpected output %s", got)
+	}
+
+	var out Account
+	if err := api.Unmarshal(got, &out); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if out != in {
+		t.Errorf("round trip mismatch: %+v", out)
+	}
+
+	custom := Config{NamingStrategy: strings.ToUpper}.Freeze()
+	got, _ = custom.Marshal(in)
+	if string(got) != `{"ACCOUNTID":7,"name":"x","ISVERIFIED":true}` {
+		t.Errorf("unexpected output %s", got)
+	}
+
+	// The default API keeps Go names.
+	got, _ = Marshal(in)
+	if string(got) != `{"AccountID":7,"name":"x","IsVerified":true}` {
+		t.Errorf("unexpected output %s", got)
+	}
+}
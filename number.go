@@ -0,0 +1,69 @@
+package fastjson
+
+import (
+	"reflect"
+	"strconv"
+	"unsafe"
+)
+
+// Number is the text of a JSON number. Interface values hold it instead of
+// float64 under DecodeOptions.UseNumber, and struct fields of type Number
+// are always decoded and encoded verbatim.
+type Number string
+
+var numberType = reflect.TypeFor[Number]()
+
+func (n Number) String() string { return string(n) }
+
+// Float64 returns the number as a float64.
+func (n Number) Float64() (float64, error) {
+	return strconv.ParseFloat(string(n), 64)
+}
+
+// Int64 returns the number as an int64.
+func (n Number) Int64() (int64, error) {
+	return strconv.ParseInt(string(n), 10, 64)
+}
+
+// readNumber reads a number without converting it. It applies the same
+// checks as ReadFloat64 except range, so 1e400 is kept as written.
+func (it *Iterator) readNumber() (Number, error) {
+	it.skipWhiteSpace()
+	start := it.head
+	if err := it.skipNumberStrict(); err != nil {
+		return "", err
+	}
+	if max := it.opts.MaxNumberLength; max > 0 && it.head-start > max {
+		return "", it.limitError("MaxNumberLength", max)
+	}
+	return Number(it.data[start:it.head]), nil
+}
+
+func decodeNumber(it *Iterator, p unsafe.Pointer) error {
+	it.skipWhiteSpace()
+	if !isNumberStart(it.char()) {
+		return it.typeError(numberType)
+	}
+	n, err := it.readNumber()
+	if err != nil {
+		return err
+	}
+	*(*Number)(p) = n
+	return nil
+}
+
+func encodeNumber(w *Writer, p unsafe.Pointer) error {
+	n := *(*Number)(p)
+	if n == "" {
+		// The zero value, like encoding/json.
+		w.WriteByte('0')
+		return nil
+	}
+
+	it := NewIterator([]byte(n))
+	if err := it.skipNumberStrict(); err != nil || it.head != it.dataLen {
+		return &UnsupportedValueError{Value: reflect.ValueOf(n), Str: strconv.Quote(string(n))}
+	}
+	w.WriteString(string(n))
+	return nil
+}
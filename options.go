@@ -0,0 +1,182 @@
+package fastjson
+
+// DuplicateKeyPolicy selects what happens when an object repeats a key.
+type DuplicateKeyPolicy uint8
+
+const (
+	// DuplicateKeyLastWins keeps the value of the last occurrence, like
+	// encoding/json. It is the default.
+	DuplicateKeyLastWins DuplicateKeyPolicy = iota
+	// DuplicateKeyFirstWins keeps the first occurrence and skips the rest.
+	DuplicateKeyFirstWins
+	// DuplicateKeyReject fails decoding with a *DuplicateKeyError.
+	DuplicateKeyReject
+)
+
+// UTF8Policy selects how invalid UTF-8 inside strings is handled.
+type UTF8Policy uint8
+
+const (
+	// UTF8Allow passes bytes through unchecked. It is the default.
+	UTF8Allow UTF8Policy = iota
+	// UTF8Reject fails with an error naming the offset of the bad byte.
+	UTF8Reject
+	// UTF8Replace substitutes U+FFFD for each invalid byte, like
+	// encoding/json.
+	UTF8Replace
+)
+
+// NonFinitePolicy selects how NaN and ±Inf, which JSON cannot represent,
+// are encoded.
+type NonFinitePolicy uint8
+
+const (
+	// NonFiniteError fails with an *UnsupportedValueError. It is the default.
+	NonFiniteError NonFinitePolicy = iota
+	// NonFiniteString writes "NaN", "Infinity" and "-Infinity" as strings,
+	// which JavaScript's Number() understands.
+	NonFiniteString
+	// NonFiniteNull writes null.
+	NonFiniteNull
+)
+
+// DefaultMaxDepth is the nesting limit applied when DecodeOptions.MaxDepth
+// is zero. It matches encoding/json.
+const DefaultMaxDepth = 10000
+
+// DecodeOptions controls how an Iterator validates its input.
+// The zero value matches the behavior of Unmarshal.
+type DecodeOptions struct {
+	DuplicateKeys DuplicateKeyPolicy
+
+	// MaxDepth bounds how deeply objects and arrays may nest.
+	// Zero means DefaultMaxDepth.
+	MaxDepth int
+
+	// The remaining limits guard against hostile payloads. Zero means
+	// unlimited. String and number lengths are measured in bytes.
+	MaxInputSize     int
+	MaxStringLength  int
+	MaxNumberLength  int
+	MaxArrayElements int
+	MaxObjectKeys    int
+
+	// CollectErrors makes type mismatches inside structs, slices and maps
+	// non-fatal: each is recorded, the offending value is skipped and
+	// decoding continues. Unmarshal then returns all of them joined.
+	CollectErrors bool
+
+	// Strict enforces the full RFC 8259 grammar, as Validate does, in every
+	// read including SkipValue, and makes Unmarshal reject trailing
+	// non-whitespace after the top-level value.
+	Strict bool
+
+	// DisallowUnknownFields fails with an *UnknownFieldError when an object
+	// has a key that matches no field of the destination struct.
+	DisallowUnknownFields bool
+
+	// UseNumber decodes numbers inside interface values as Number rather
+	// than float64, preserving their exact text.
+	UseNumber bool
+
+	// InvalidUTF8 selects what happens to invalid UTF-8 in strings and
+	// keys. Under UTF8Reject reads fail with a *SyntaxError at the bad
+	// byte. The default, UTF8Allow, lets it through unchecked.
+	InvalidUTF8 UTF8Policy
+}
+
+// SetOptions changes how subsequent reads validate input.
+func (it *Iterator) SetOptions(opts DecodeOptions) {
+	it.opts = opts
+	it.table = &stringTable
+	if opts.InvalidUTF8 != UTF8Allow {
+		it.table = &utf8Table
+	}
+}
+
+func (it *Iterator) limitError(limit string, max int) error {
+	return &LimitError{Limit: limit, Max: max, Offset: it.head}
+}
+
+// checkInputSize enforces MaxInputSize against the whole buffer.
+func (it *Iterator) checkInputSize() error {
+	if max := it.opts.MaxInputSize; max > 0 && it.dataLen > max {
+		return &LimitError{Limit: "MaxInputSize", Max: max}
+	}
+	return nil
+}
+
+// collect records a type mismatch instead of failing when CollectErrors is
+// set, skipping the offending value so that decoding can continue. Any other
+// error is returned unchanged.
+func (it *Iterator) collect(err error) error {
+	if !it.opts.CollectErrors {
+		return err
+	}
+	if _, ok := err.(*UnmarshalTypeError); !ok {
+		return err
+	}
+	if err := it.SkipValue(); err != nil {
+		return err
+	}
+	it.errs = append(it.errs, err)
+	return nil
+}
+
+// EncodeOptions controls how a Writer formats its output.
+// The zero value matches the behavior of Marshal.
+type EncodeOptions struct {
+	// InvalidUTF8 selects what happens to invalid UTF-8 in strings and
+	// keys. Under UTF8Reject encoding fails with an *InvalidUTF8Error. The
+	// default, UTF8Allow, copies it to the output unchanged.
+	InvalidUTF8 UTF8Policy
+
+	// EscapeNonBMP writes characters above U+FFFF as escaped UTF-16
+	// surrogate pairs (e.g. "\ud83d\ude00") for consumers that cannot
+	// handle 4-byte UTF-8 sequences.
+	EscapeNonBMP bool
+
+	// EscapeHTML escapes '<', '>' and '&' as \u003c, \u003e and \u0026,
+	// and U+2028 and U+2029 as \u2028 and \u2029, so that output can be
+	// embedded in HTML <script> tags and JSONP like encoding/json's default.
+	EscapeHTML bool
+
+	// ASCIIOnly escapes every non-ASCII character as \uXXXX, with surrogate
+	// pairs above U+FFFF, for consumers that cannot handle raw UTF-8.
+	ASCIIOnly bool
+
+	// Prefix and Indent turn on pretty-printing: each element of an object
+	// or array starts on a new line beginning with Prefix followed by one
+	// copy of Indent per level of nesting. See MarshalIndent.
+	Prefix string
+	Indent string
+
+	// NonFinite selects how NaN, +Inf and -Inf are encoded, as JSON has no
+	// numbers for them. The default, NonFiniteError, fails with an
+	// *UnsupportedValueError like encoding/json; NonFiniteString writes
+	// them as the strings "NaN", "Infinity" and "-Infinity"; NonFiniteNull
+	// writes null.
+	NonFinite NonFinitePolicy
+
+	// SortMapKeys writes map entries in increasing key order, like
+	// encoding/json, instead of Go's randomized iteration order.
+	SortMapKeys bool
+}
+
+// SetOptions changes how subsequent writes format their output.
+func (w *Writer) SetOptions(opts EncodeOptions) {
+	w.opts = opts
+	w.pretty = opts.Prefix != "" || opts.Indent != ""
+	w.level = 0
+	inspectUTF8 := opts.InvalidUTF8 != UTF8Allow || opts.EscapeNonBMP || opts.ASCIIOnly
+	switch {
+	case opts.EscapeHTML && inspectUTF8:
+		w.table = &htmlUTF8Table
+	case opts.EscapeHTML:
+		w.table = &htmlTable
+	case inspectUTF8:
+		w.table = &utf8Table
+	default:
+		w.table = &stringTable
+	}
+}
@@ -0,0 +1,247 @@
+package fastjson
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestUnmarshal_DuplicateKeys(t *testing.T) {
+	input := []byte(`{"id": 1, "name": "a", "id": 2}`)
+
+	t.Run("LastWins", func(t *testing.T) {
+		var u User
+		if err := Unmarshal(input, &u); err != nil {
+			t.Fatalf("Unmarshal failed: %v", err)
+		}
+		if u.ID != 2 {
+			t.Errorf("expected 2, got %d", u.ID)
+		}
+	})
+
+	t.Run("FirstWins", func(t *testing.T) {
+		var u User
+		opts := DecodeOptions{DuplicateKeys: DuplicateKeyFirstWins}
+		if err := UnmarshalWithOptions(input, &u, opts); err != nil {
+			t.Fatalf("Unmarshal failed: %v", err)
+		}
+		if u.ID != 1 {
+			t.Errorf("expected 1, got %d", u.ID)
+		}
+	})
+
+	t.Run("Reject", func(t *testing.T) {
+		var u User
+		opts := DecodeOptions{DuplicateKeys: DuplicateKeyReject}
+		err := UnmarshalWithOptions(input, &u, opts)
+
+		var dupErr *DuplicateKeyError
+		if !errors.As(err, &dupErr) {
+			t.Fatalf("expected *DuplicateKeyError, got %v", err)
+		}
+		if dupErr.Key != "id" || dupErr.First != 1 || dupErr.Offset != 23 {
+			t.Errorf("unexpected error details: %+v", dupErr)
+		}
+	})
+}
+
+func TestUnmarshal_DuplicateUnknownKey(t *testing.T) {
+	input := []byte(`{"id": 1, "extra": 1, "extra": 2}`)
+	opts := DecodeOptions{DuplicateKeys: DuplicateKeyReject}
+
+	var u User
+	var dupErr *DuplicateKeyError
+	if err := UnmarshalWithOptions(input, &u, opts); !errors.As(err, &dupErr) {
+		t.Fatalf("expected *DuplicateKeyError, got %v", err)
+	}
+	if dupErr.Key != "extra" || dupErr.First != 10 || dupErr.Offset != 22 {
+		t.Errorf("unexpected error details: %+v", dupErr)
+	}
+
+	// Distinct unknown keys are still skipped, and known keys cost no more
+	// than under the default policy.
+	input = []byte(`{"id": 1, "extra": 1, "other": 2}`)
+	if err := UnmarshalWithOptions(input, &u, opts); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	known := []byte(`{"id": 1, "name": "a"}`)
+	base := testing.AllocsPerRun(100, func() {
+		_ = Unmarshal(known, &u)
+	})
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = UnmarshalWithOptions(known, &u, opts)
+	})
+	if allocs != base {
+		t.Errorf("Unmarshal allocated %v times per run under Reject, want %v", allocs, base)
+	}
+}
+
+func TestUnmarshal_DuplicateKeysMapAndAny(t *testing.T) {
+	input := []byte(`{"a": 1, "b": 2, "a": 3}`)
+
+	var m map[string]int
+	opts := DecodeOptions{DuplicateKeys: DuplicateKeyFirstWins}
+	if err := UnmarshalWithOptions(input, &m, opts); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if m["a"] != 1 || len(m) != 2 {
+		t.Errorf("unexpected map: %v", m)
+	}
+
+	var v any
+	if err := UnmarshalWithOptions(input, &v, opts); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if v.(map[string]any)["a"] != 1.0 {
+		t.Errorf("unexpected value: %v", v)
+	}
+
+	opts.DuplicateKeys = DuplicateKeyReject
+	var dupErr *DuplicateKeyError
+	if err := UnmarshalWithOptions(input, &m, opts); !errors.As(err, &dupErr) {
+		t.Errorf("expected *DuplicateKeyError for map, got %v", err)
+	}
+	if err := UnmarshalWithOptions(input, &v, opts); !errors.As(err, &dupErr) {
+		t.Errorf("expected *DuplicateKeyError for any, got %v", err)
+	}
+}
+
+func TestUnmarshal_MaxDepth(t *testing.T) {
+	deep := []byte(strings.Repeat("[", 1_000_000) + strings.Repeat("]", 1_000_000))
+
+	var v any
+	var depthErr *MaxDepthError
+	if err := Unmarshal(deep, &v); !errors.As(err, &depthErr) {
+		t.Fatalf("expected *MaxDepthError for any, got %v", err)
+	}
+	if depthErr.Max != DefaultMaxDepth {
+		t.Errorf("expected default limit, got %d", depthErr.Max)
+	}
+
+	var nested [][][]int
+	opts := DecodeOptions{MaxDepth: 2}
+	if err := UnmarshalWithOptions([]byte(`[[[1]]]`), &nested, opts); !errors.As(err, &depthErr) {
+		t.Errorf("expected *MaxDepthError for slices, got %v", err)
+	}
+	if err := UnmarshalWithOptions([]byte(`[[]]`), &nested, opts); err != nil {
+		t.Errorf("unexpected error at the limit: %v", err)
+	}
+
+	var u User
+	input := []byte(`{"id": 1, "extra": {"a": [[1]]}}`)
+	if err := UnmarshalWithOptions(input, &u, DecodeOptions{MaxDepth: 3}); !errors.As(err, &depthErr) {
+		t.Errorf("expected *MaxDepthError from SkipValue, got %v", err)
+	}
+	if err := UnmarshalWithOptions(input, &u, DecodeOptions{MaxDepth: 4}); err != nil {
+		t.Errorf("unexpected error at the limit: %v", err)
+	}
+}
+
+func TestUnmarshal_Limits(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		opts  DecodeOptions
+		limit string
+		v     any
+	}{
+		{"InputSize", `{"id": 1}`, DecodeOptions{MaxInputSize: 5}, "MaxInputSize", &User{}},
+		{"StringLength", `{"name": "abcdef"}`, DecodeOptions{MaxStringLength: 5}, "MaxStringLength", &User{}},
+		{"EscapedStringLength", `{"name": "ab\ncdef"}`, DecodeOptions{MaxStringLength: 5}, "MaxStringLength", &User{}},
+		{"IntLength", `{"id": 123456}`, DecodeOptions{MaxNumberLength: 5}, "MaxNumberLength", &User{}},
+		{"FloatLength", `[1.23456]`, DecodeOptions{MaxNumberLength: 5}, "MaxNumberLength", new(any)},
+		{"SliceElements", `[1, 2, 3]`, DecodeOptions{MaxArrayElements: 2}, "MaxArrayElements", new([]int)},
+		{"GenericElements", `[1, 2, 3]`, DecodeOptions{MaxArrayElements: 2}, "MaxArrayElements", new(any)},
+		{"MapKeys", `{"a": 1, "b": 2, "c": 3}`, DecodeOptions{MaxObjectKeys: 2}, "MaxObjectKeys", new(map[string]int)},
+		{"GenericKeys", `{"a": 1, "b": 2, "c": 3}`, DecodeOptions{MaxObjectKeys: 2}, "MaxObjectKeys", new(any)},
+		{"StructKeys", `{"id": 1, "x": 2, "name": "a"}`, DecodeOptions{MaxObjectKeys: 2}, "MaxObjectKeys", &User{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := UnmarshalWithOptions([]byte(tt.input), tt.v, tt.opts)
+			var limitErr *LimitError
+			if !errors.As(err, &limitErr) {
+				t.Fatalf("expected *LimitError, got %v", err)
+			}
+			if limitErr.Limit != tt.limit {
+				t.Errorf("expected %s, got %s", tt.limit, limitErr.Limit)
+			}
+		})
+	}
+
+	var m map[string]int
+	opts := DecodeOptions{MaxObjectKeys: 3, MaxArrayElements: 3}
+	if err := UnmarshalWithOptions([]byte(`{"a": 1, "b": 2, "c": 3}`), &m, opts); err != nil {
+		t.Errorf("unexpected error at the limit: %v", err)
+	}
+	var u User
+	if err := UnmarshalWithOptions([]byte(`{"id": 1, "x": 2, "name": "a"}`), &u, opts); err != nil {
+		t.Errorf("unexpected error at the limit: %v", err)
+	}
+
+	// An escaped string fails once it outgrows the limit, not at its end.
+	long := `{"name": "\n` + strings.Repeat("a", 1000) + `"}`
+	var limitErr *LimitError
+	err := UnmarshalWithOptions([]byte(long), &u, DecodeOptions{MaxStringLength: 5})
+	if !errors.As(err, &limitErr) || limitErr.Offset > 20 {
+		t.Errorf("expected a MaxStringLength error near the start, got %v", err)
+	}
+}
+
+func TestUnmarshal_CollectErrors(t *testing.T) {
+	input := []byte(`{
+		"status": "ok",
+		"message": "hello",
+		"data": [
+			{"id": 1, "username": 7, "roles": ["a", false]},
+			{"id": "two", "preferences": {"theme": 1, "lang": "en"}}
+		],
+		"meta": {"page": 2}
+	}`)
+
+	var resp APIResponse
+	err := UnmarshalWithOptions(input, &resp, DecodeOptions{CollectErrors: true})
+	if err == nil {
+		t.Fatal("expected errors")
+	}
+
+	var paths []string
+	for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var typeErr *UnmarshalTypeError
+		if !errors.As(e, &typeErr) {
+			t.Fatalf("unexpected error: %v", e)
+		}
+		paths = append(paths, typeErr.Path)
+	}
+
+	expected := []string{
+		"$.status",
+		"$.data[0].username",
+		"$.data[0].roles[1]",
+		"$.data[1].id",
+		"$.data[1].preferences.theme",
+	}
+	if strings.Join(paths, " ") != strings.Join(expected, " ") {
+		t.Errorf("unexpected paths:\n%v\nwant:\n%v", paths, expected)
+	}
+
+	// Valid fields around the bad ones are still decoded.
+	if resp.Message != "hello" || resp.Meta.Page != 2 || len(resp.Data) != 2 {
+		t.Errorf("valid fields not decoded: %+v", resp)
+	}
+	if resp.Data[1].Preferences["lang"] != "en" {
+		t.Errorf("expected lang preference, got %v", resp.Data[1].Preferences)
+	}
+}
+
+func TestUnmarshal_CollectErrorsSyntax(t *testing.T) {
+	var u User
+	err := UnmarshalWithOptions([]byte(`{"id": "x", "name": }`), &u, DecodeOptions{CollectErrors: true})
+
+	var typeErr *UnmarshalTypeError
+	var synErr *SyntaxError
+	if !errors.As(err, &typeErr) || !errors.As(err, &synErr) {
+		t.Errorf("expected both the type and the syntax error, got %v", err)
+	}
+}
@@ -16,6 +16,10 @@ func TestReadInt64(t *testing.T) {
 		{"Negative", "-123", -123, false},
 		{"Zero", "0", 0, false},
 		{"Large", "9223372036854775807", 9223372036854775807, false},
+		{"Smallest", "-9223372036854775808", math.MinInt64, false},
+		{"Past Max", "9223372036854775808", 0, true},
+		{"Past Min", "-9223372036854775809", 0, true},
+		{"Uint64 Sized", "18446744073709551617", 0, true},
 		{"With Space", "  42 ", 42, false},
 		{"Leading Zero", "01", 0, true},   // Invalid JSON
 		{"Positive Sign", "+1", 0, true},  // Invalid JSON
@@ -0,0 +1,177 @@
+package fastjson
+
+// Compact appends to dst the JSON in src with insignificant whitespace
+// removed. Strings and numbers are copied byte for byte, escapes included.
+// src is validated as it is copied; on error dst is left unchanged.
+func Compact(dst *Writer, src []byte) error {
+	return reformat(dst, src, "", "")
+}
+
+// Indent appends to dst an indented form of the JSON in src, laid out as
+// MarshalIndent would with the same prefix and indent. Like Compact, it
+// preserves strings and numbers exactly and leaves dst unchanged on error.
+func Indent(dst *Writer, src []byte, prefix, indent string) error {
+	return reformat(dst, src, prefix, indent)
+}
+
+func reformat(dst *Writer, src []byte, prefix, indent string) error {
+	saved := dst.opts
+	start := len(dst.Buffer)
+	defer dst.SetOptions(saved)
+
+	dst.SetOptions(EncodeOptions{Prefix: prefix, Indent: indent})
+
+	it := NewIterator(src)
+	it.opts.Strict = true
+	err := it.copyValue(dst)
+	if err == nil {
+		err = it.checkEnd()
+	}
+	if err != nil {
+		dst.Buffer = dst.Buffer[:start]
+		return err
+	}
+	return nil
+}
+
+// copyValue validates one value like skipStrict while writing it to w.
+func (it *Iterator) copyValue(w *Writer) error {
+	it.skipWhiteSpace()
+	if it.head >= it.dataLen {
+		return it.expected("value")
+	}
+
+	start := it.head
+	var err error
+	switch c := it.data[it.head]; {
+	case c == '{':
+		return it.copyObject(w)
+	case c == '[':
+		return it.copyArray(w)
+	case c == '"':
+		err = it.skipStringStrict()
+	case c == 't':
+		err = it.skipLiteral("true")
+	case c == 'f':
+		err = it.skipLiteral("false")
+	case c == 'n':
+		err = it.skipLiteral("null")
+	case isNumberStart(c):
+		err = it.skipNumberStrict()
+	default:
+		return it.expected("value")
+	}
+
+	if err != nil {
+		return err
+	}
+	w.Write(it.data[start:it.head])
+	return nil
+}
+
+func (it *Iterator) copyObject(w *Writer) error {
+	if err := it.ReadObjectStart(); err != nil {
+		return err
+	}
+
+	it.skipWhiteSpace()
+	if it.char() == '}' {
+		it.head++
+		it.depth--
+		w.WriteString("{}")
+		return nil
+	}
+
+	if w.pretty {
+		w.openIndent('{')
+	} else {
+		w.WriteByte('{')
+	}
+	for {
+		it.skipWhiteSpace()
+		if it.char() != '"' {
+			return it.expected("string")
+		}
+		if w.pretty {
+			w.writeIndent()
+		}
+		if err := it.copyValue(w); err != nil {
+			return err
+		}
+		if err := it.ReadColon(); err != nil {
+			return err
+		}
+		if w.pretty {
+			w.WriteString(": ")
+		} else {
+			w.WriteByte(':')
+		}
+		if err := it.copyValue(w); err != nil {
+			return err
+		}
+
+		it.skipWhiteSpace()
+		switch it.char() {
+		case ',':
+			it.head++
+			w.WriteByte(',')
+		case '}':
+			it.head++
+			it.depth--
+			if w.pretty {
+				w.closeIndent('}')
+			} else {
+				w.WriteByte('}')
+			}
+			return nil
+		default:
+			return it.expected("',' or '}'")
+		}
+	}
+}
+
+func (it *Iterator) copyArray(w *Writer) error {
+	if err := it.ReadArrayStart(); err != nil {
+		return err
+	}
+
+	it.skipWhiteSpace()
+	if it.char() == ']' {
+		it.head++
+		it.depth--
+		w.WriteString("[]")
+		return nil
+	}
+
+	if w.pretty {
+		w.openIndent('[')
+	} else {
+		w.WriteByte('[')
+	}
+	for {
+		if w.pretty {
+			w.writeIndent()
+		}
+		if err := it.copyValue(w); err != nil {
+			return err
+		}
+
+		it.skipWhiteSpace()
+		switch it.char() {
+		case ',':
+			it.head++
+			w.WriteByte(',')
+		case ']':
+			it.head++
+			it.depth--
+			if w.pretty {
+				w.closeIndent(']')
+			} else {
+				w.WriteByte(']')
+			}
+			return nil
+		default:
+			return it.expected("',' or ']'")
+		}
+	}
+}
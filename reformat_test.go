@@ -0,0 +1,67 @@
+package fastjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+var reformatInputs = []string{
+	`{}`,
+	` [ ] `,
+	`{"a": [1, 2.50, -0e+3, {"b": null}], "cé\n": "x\/y", "d": {}, "e": [], "f": true}`,
+	"\t\"string\"\n",
+	`[[[[]]], {"k": [false]}]`,
+}
+
+func TestCompact(t *testing.T) {
+	for _, input := range reformatInputs {
+		var expected bytes.Buffer
+		if err := json.Compact(&expected, []byte(input)); err != nil {
+			t.Fatalf("json.Compact failed: %v", err)
+		}
+
+		w := GetWriter()
+		if err := Compact(w, []byte(input)); err != nil {
+			t.Fatalf("Compact(%q) failed: %v", input, err)
+		}
+		if string(w.Buffer) != expected.String() {
+			t.Errorf("Expected %s, got %s", expected.String(), w.Buffer)
+		}
+		PutWriter(w)
+	}
+}
+
+func TestIndent(t *testing.T) {
+	for _, input := range reformatInputs {
+		var expected bytes.Buffer
+		if err := json.Indent(&expected, []byte(input), "", "  "); err != nil {
+			t.Fatalf("json.Indent failed: %v", err)
+		}
+
+		w := GetWriter()
+		if err := Indent(w, []byte(input), "", "  "); err != nil {
+			t.Fatalf("Indent(%q) failed: %v", input, err)
+		}
+		// encoding/json keeps whitespace around the top-level value; we don't.
+		if string(w.Buffer) != string(bytes.TrimSpace(expected.Bytes())) {
+			t.Errorf("Expected:\n%s\nGot:\n%s", expected.String(), w.Buffer)
+		}
+		PutWriter(w)
+	}
+}
+
+func TestCompact_Invalid(t *testing.T) {
+	w := GetWriter()
+	defer PutWriter(w)
+
+	w.WriteString("prefix")
+	for _, input := range []string{`{"a": tru}`, `[1,]`, `{} x`, `01`} {
+		if err := Compact(w, []byte(input)); err == nil {
+			t.Errorf("expected error for %q", input)
+		}
+		if string(w.Buffer) != "prefix" {
+			t.Errorf("dst modified on error: %s", w.Buffer)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package fastjson
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// typeCodecs holds encoders and decoders registered for specific types,
+// which take precedence over the ones compiled from the type's Kind.
+type typeCodecs struct {
+	encoders sync.Map // reflect.Type -> EncoderFunc
+	decoders sync.Map // reflect.Type -> DecoderFunc
+	unions   sync.Map // reflect.Type -> *union
+
+	// gen counts registrations. Cached codecs may have compiled a
+	// registered codec into a closure, so they are only valid for the
+	// generation they were compiled in.
+	gen atomic.Uint64
+}
+
+var globalCodecs typeCodecs
+
+// RegisterTypeEncoder makes every API encode values of type t with enc,
+// including where t appears as a field, element or pointee of another type.
+// Codecs registered on an API itself take precedence. A nil enc removes the
+// registration.
+//
+// Registering invalidates every cached codec, so it is best done during
+// initialization.
+func RegisterTypeEncoder(t reflect.Type, enc EncoderFunc) {
+	globalCodecs.registerEncoder(t, enc)
+}
+
+// RegisterTypeDecoder is the decoding counterpart of RegisterTypeEncoder.
+func RegisterTypeDecoder(t reflect.Type, dec DecoderFunc) {
+	globalCodecs.registerDecoder(t, dec)
+}
+
+// RegisterTypeEncoder is like the package-level RegisterTypeEncoder but
+// only affects a. It panics on ConfigCompatibleWithStandardLibrary and
+// ConfigFastest.
+func (a *API) RegisterTypeEncoder(t reflect.Type, enc EncoderFunc) {
+	a.checkRegister()
+	a.codecs.registerEncoder(t, enc)
+}
+
+// RegisterTypeDecoder is like the package-level RegisterTypeDecoder but
+// only affects a.
+func (a *API) RegisterTypeDecoder(t reflect.Type, dec DecoderFunc) {
+	a.checkRegister()
+	a.codecs.registerDecoder(t, dec)
+}
+
+// checkRegister panics if a is one of the shared package-level APIs.
+func (a *API) checkRegister() {
+	if a.shared != "" {
+		panic("fastjson: cannot register codecs on the shared " + a.shared + "; use " + a.shared + ".Config().Freeze()")
+	}
+}
+
+func (c *typeCodecs) registerEncoder(t reflect.Type, enc EncoderFunc) {
+	if enc == nil {
+		c.encoders.Delete(t)
+	} else {
+		c.encoders.Store(t, enc)
+	}
+	c.gen.Add(1)
+}
+
+func (c *typeCodecs) registerDecoder(t reflect.Type, dec DecoderFunc) {
+	if dec == nil {
+		c.decoders.Delete(t)
+	} else {
+		c.decoders.Store(t, dec)
+	}
+	c.gen.Add(1)
+}
+
+// generation identifies the registrations visible to a. Both counters only
+// grow, so their sum changes whenever either does.
+func (a *API) generation() uint64 {
+	return globalCodecs.gen.Load() + a.codecs.gen.Load()
+}
+
+// registeredEncoder returns the encoder registered for t, if any.
+func (a *API) registeredEncoder(t reflect.Type) (EncoderFunc, bool) {
+	if f, ok := a.codecs.encoders.Load(t); ok {
+		return f.(EncoderFunc), true
+	}
+	if f, ok := globalCodecs.encoders.Load(t); ok {
+		return f.(EncoderFunc), true
+	}
+	return nil, false
+}
+
+// registeredDecoder returns the decoder registered for t, if any.
+func (a *API) registeredDecoder(t reflect.Type) (DecoderFunc, bool) {
+	if f, ok := a.codecs.decoders.Load(t); ok {
+		return f.(DecoderFunc), true
+	}
+	if f, ok := globalCodecs.decoders.Load(t); ok {
+		return f.(DecoderFunc), true
+	}
+	return nil, false
+}
+
+// cachedEncoder and cachedDecoder are the values of API's codec caches.
+type cachedEncoder struct {
+	gen uint64
+	enc EncoderFunc
+}
+
+type cachedDecoder struct {
+	gen uint64
+	dec DecoderFunc
+}
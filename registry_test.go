@@ -0,0 +1,117 @@
+package fastjson
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+// cents is a stand-in for a third-party money type.
+type cents int64
+
+type Invoice struct {
+	Total cents   `json:"total"`
+	Items []cents `json:"items"`
+	Tip   *cents  `json:"tip"`
+}
+
+var centsType = reflect.TypeFor[cents]()
+
+func encodeCents(w *Writer, p unsafe.Pointer) error {
+	c := *(*cents)(p)
+	w.WriteStringEscaped(fmt.Sprintf("%d.%02d", c/100, c%100))
+	return nil
+}
+
+func decodeCents(it *Iterator, p unsafe.Pointer) error {
+	s, err := it.ReadString()
+	if err != nil {
+		return err
+	}
+	var whole, frac int64
+	if _, err := fmt.Sscanf(s, "%d.%d", &whole, &frac); err != nil {
+		return err
+	}
+	*(*cents)(p) = cents(whole*100 + frac)
+	return nil
+}
+
+func TestRegisterType_Global(t *testing.T) {
+	tip := cents(50)
+	inv := Invoice{Total: 1250, Items: []cents{1000, 250}, Tip: &tip}
+
+	// Compile and cache the Invoice encoder before registering.
+	before, err := Marshal(inv)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if string(before) != `{"total":1250,"items":[1000,250],"tip":50}` {
+		t.Errorf("unexpected output %s", before)
+	}
+	if generic, _ := MarshalT(&inv); string(generic) != string(before) {
+		t.Errorf("MarshalT = %s, want %s", generic, before)
+	}
+
+	RegisterTypeEncoder(centsType, encodeCents)
+	RegisterTypeDecoder(centsType, decodeCents)
+	t.Cleanup(func() {
+		RegisterTypeEncoder(centsType, nil)
+		RegisterTypeDecoder(centsType, nil)
+	})
+
+	after, err := Marshal(inv)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	expected := `{"total":"12.50","items":["10.00","2.50"],"tip":"0.50"}`
+	if string(after) != expected {
+		t.Errorf("Expected %s, got %s", expected, after)
+	}
+	if generic, _ := MarshalT(&inv); string(generic) != expected {
+		t.Errorf("MarshalT kept the stale encoder: %s", generic)
+	}
+	if generic, err := UnmarshalT[Invoice](after); err != nil || !reflect.DeepEqual(generic, inv) {
+		t.Errorf("UnmarshalT = %+v, %v", generic, err)
+	}
+
+	var decoded Invoice
+	if err := Unmarshal(after, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, inv) {
+		t.Errorf("round trip mismatch: %+v", decoded)
+	}
+}
+
+func TestRegisterType_PerAPI(t *testing.T) {
+	api := Config{}.Freeze()
+	api.RegisterTypeEncoder(centsType, func(w *Writer, p unsafe.Pointer) error {
+		w.WriteString(strings.Repeat("1", int(*(*cents)(p))))
+		return nil
+	})
+
+	got, err := api.Marshal(Invoice{Total: 3})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if string(got) != `{"total":111,"items":null,"tip":null}` {
+		t.Errorf("unexpected output %s", got)
+	}
+
+	// Other APIs are unaffected.
+	got, err = Marshal(Invoice{Total: 3})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if string(got) != `{"total":3,"items":null,"tip":null}` {
+		t.Errorf("unexpected output %s", got)
+	}
+
+	api.RegisterTypeEncoder(centsType, nil)
+	got, _ = api.Marshal(Invoice{Total: 3})
+	if string(got) != `{"total":3,"items":null,"tip":null}` {
+		t.Errorf("expected registration removed, got %s", got)
+	}
+}
@@ -0,0 +1,164 @@
+package fastjson
+
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+// Encoder writes a stream of JSON values to an io.Writer.
+type Encoder struct {
+	api  *API
+	w    io.Writer
+	opts EncodeOptions
+}
+
+// NewEncoder returns an Encoder writing to w with the default options.
+func NewEncoder(w io.Writer) *Encoder {
+	return defaultAPI.NewEncoder(w)
+}
+
+// Encode writes the encoding of v followed by a newline. Nothing is
+// written if encoding fails.
+func (e *Encoder) Encode(v any) error {
+	w := e.api.getWriter(e.opts)
+	defer PutWriter(w)
+
+	if err := e.api.encode(w, v); err != nil {
+		return err
+	}
+	w.WriteByte('\n')
+	_, err := e.w.Write(w.Buffer)
+	return err
+}
+
+// SetIndent makes subsequent values indented as by MarshalIndent.
+func (e *Encoder) SetIndent(prefix, indent string) {
+	e.opts.Prefix, e.opts.Indent = prefix, indent
+}
+
+// SetEscapeHTML sets EncodeOptions.EscapeHTML for subsequent values.
+func (e *Encoder) SetEscapeHTML(on bool) {
+	e.opts.EscapeHTML = on
+}
+
+// Decoder reads a stream of JSON values from an io.Reader. Values may be
+// separated by whitespace or nothing at all, as in `{}{}` or `1 2`.
+type Decoder struct {
+	api  *API
+	r    io.Reader
+	opts DecodeOptions
+
+	// buf holds input read but not yet decoded, starting at scanp. Decoded
+	// strings alias buf, so bytes before scanp are never overwritten.
+	buf   []byte
+	scanp int
+	err   error // first error from r, io.EOF included
+}
+
+// NewDecoder returns a Decoder reading from r with the default options.
+func NewDecoder(r io.Reader) *Decoder {
+	return defaultAPI.NewDecoder(r)
+}
+
+// DisallowUnknownFields sets DecodeOptions.DisallowUnknownFields.
+func (d *Decoder) DisallowUnknownFields() {
+	d.opts.DisallowUnknownFields = true
+}
+
+// UseNumber sets DecodeOptions.UseNumber.
+func (d *Decoder) UseNumber() {
+	d.opts.UseNumber = true
+}
+
+// Decode reads the next value from the stream and stores it in v. It
+// returns io.EOF when the stream ends cleanly between values.
+func (d *Decoder) Decode(v any) error {
+	n, err := d.readValue()
+	if err != nil {
+		return err
+	}
+
+	data := d.buf[d.scanp : d.scanp+n]
+	d.scanp += n
+	return d.api.unmarshal(data, v, d.opts)
+}
+
+// More reports whether there is another value in the stream.
+func (d *Decoder) More() bool {
+	for {
+		it := NewIterator(d.buf[d.scanp:])
+		it.skipWhiteSpace()
+		if it.head < it.dataLen {
+			return true
+		}
+		if d.err != nil {
+			return false
+		}
+		d.refill()
+	}
+}
+
+// Buffered returns a reader of the data read from r but not yet decoded.
+func (d *Decoder) Buffered() io.Reader {
+	return bytes.NewReader(d.buf[d.scanp:])
+}
+
+// truncationSlack is how close to the end of the buffered data a syntax
+// error must be for it to possibly come from a read that stopped in the
+// middle of a token, such as "tru" or "\u12".
+const truncationSlack = 6
+
+// readValue buffers input until it holds one complete value and returns
+// that value's length, leading whitespace included.
+func (d *Decoder) readValue() (int, error) {
+	for {
+		data := d.buf[d.scanp:]
+		it := NewIterator(data)
+		it.SetOptions(d.opts)
+		it.opts.Strict = true
+		it.skipWhiteSpace()
+
+		if start := it.head; start < len(data) {
+			err := it.skipStrict()
+			var se *SyntaxError
+			nearEnd := errors.As(err, &se) && se.Offset >= len(data)-truncationSlack
+			switch {
+			case err == nil:
+				// A number may continue past what has been read so far.
+				if it.head < len(data) || !isNumberStart(data[start]) || d.err != nil {
+					return it.head, nil
+				}
+			case d.err != nil && d.err != io.EOF && nearEnd:
+				return 0, d.err
+			case d.err != nil || !nearEnd:
+				return 0, err
+			}
+		} else if d.err != nil {
+			return 0, d.err
+		}
+
+		if max := d.opts.MaxInputSize; max > 0 && len(data) > max {
+			return 0, &LimitError{Limit: "MaxInputSize", Max: max}
+		}
+		d.refill()
+	}
+}
+
+// refill reads more input into buf, moving the unread part to a new buffer
+// when there is too little room left.
+func (d *Decoder) refill() {
+	const minRead = 512
+	if cap(d.buf)-len(d.buf) < minRead {
+		rest := len(d.buf) - d.scanp
+		buf := make([]byte, rest, max(4096, 2*rest+minRead))
+		copy(buf, d.buf[d.scanp:])
+		d.buf, d.scanp = buf, 0
+	}
+
+	n, err := d.r.Read(d.buf[len(d.buf):cap(d.buf)])
+	d.buf = d.buf[:len(d.buf)+n]
+	if err != nil {
+		d.err = err
+	}
+}
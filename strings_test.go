@@ -1,6 +1,8 @@
 package fastjson
 
 import (
+	"encoding/json"
+	"errors"
 	"testing"
 )
 
@@ -46,3 +48,219 @@ func TestReadString(t *testing.T) {
 		})
 	}
 }
+
+func TestReadString_InvalidUTF8(t *testing.T) {
+	input := []byte("\"ok é \xff end\"")
+	escaped := []byte("\"a\\n\xfe é\"")
+
+	t.Run("Allow", func(t *testing.T) {
+		val, err := NewIterator(input).ReadString()
+		if err != nil || val != "ok é \xff end" {
+			t.Errorf("unexpected result %q, %v", val, err)
+		}
+	})
+
+	t.Run("Reject", func(t *testing.T) {
+		for _, in := range [][]byte{input, escaped} {
+			it := NewIterator(in)
+			it.SetOptions(DecodeOptions{InvalidUTF8: UTF8Reject})
+			_, err := it.ReadString()
+
+			var synErr *SyntaxError
+			if !errors.As(err, &synErr) {
+				t.Fatalf("expected *SyntaxError, got %v", err)
+			}
+			if in[synErr.Offset] < 0xfe {
+				t.Errorf("offset %d does not point at the invalid byte", synErr.Offset)
+			}
+		}
+	})
+
+	t.Run("Replace", func(t *testing.T) {
+		tests := []struct {
+			input    []byte
+			expected string
+		}{
+			{input, "ok é � end"},
+			{escaped, "a\n� é"},
+			{[]byte("\"é\""), "é"},
+		}
+		for _, tt := range tests {
+			it := NewIterator(tt.input)
+			it.SetOptions(DecodeOptions{InvalidUTF8: UTF8Replace})
+			val, err := it.ReadString()
+			if err != nil || val != tt.expected {
+				t.Errorf("expected %q, got %q, %v", tt.expected, val, err)
+			}
+		}
+	})
+}
+
+func TestWriteStringEscaped_InvalidUTF8(t *testing.T) {
+	input := "ok é \xff end"
+
+	w := GetWriter()
+	w.WriteStringEscaped(input)
+	if string(w.Buffer) != "\"ok é \xff end\"" || w.Err() != nil {
+		t.Errorf("unexpected output %q, %v", w.Buffer, w.Err())
+	}
+	PutWriter(w)
+
+	out, err := MarshalWithOptions(input, EncodeOptions{InvalidUTF8: UTF8Replace})
+	if err != nil || string(out) != `"ok é \ufffd end"` {
+		t.Errorf("unexpected output %s, %v", out, err)
+	}
+
+	var utf8Err *InvalidUTF8Error
+	if _, err := MarshalWithOptions(input, EncodeOptions{InvalidUTF8: UTF8Reject}); !errors.As(err, &utf8Err) {
+		t.Errorf("expected *InvalidUTF8Error, got %v", err)
+	} else if utf8Err.Str != input || utf8Err.Offset != 6 {
+		t.Errorf("unexpected error details: %+v", utf8Err)
+	}
+	if _, err := MarshalWithOptions("é", EncodeOptions{InvalidUTF8: UTF8Reject}); err != nil {
+		t.Errorf("unexpected error for valid UTF-8: %v", err)
+	}
+}
+
+func TestReadString_SurrogatePairs(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"Pair", `"\ud83d\ude00"`, "😀"},
+		{"Upper Hex", `"x\uD83D\uDE00y"`, "x😀y"},
+		{"Lone High", `"\ud83d!"`, "�!"},
+		{"Lone Low", `"\ude00"`, "�"},
+		{"High Then BMP", `"\ud83dA"`, "�A"},
+		{"Two Highs", `"\ud83d\ud83d\ude00"`, "�😀"},
+		{"High At End", `"\ud83d"`, "�"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			val, err := NewIterator([]byte(tt.input)).ReadString()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if val != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, val)
+			}
+		})
+	}
+
+	it := NewIterator([]byte(`"ab\ud83d!"`))
+	it.SetOptions(DecodeOptions{Strict: true})
+	_, err := it.ReadString()
+	var synErr *SyntaxError
+	if !errors.As(err, &synErr) || synErr.Offset != 3 {
+		t.Errorf("expected *SyntaxError at offset 3, got %v", err)
+	}
+}
+
+func TestWriteStringEscaped_NonBMP(t *testing.T) {
+	out, err := MarshalWithOptions("é😀", EncodeOptions{EscapeNonBMP: true})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if string(out) != `"é\ud83d\ude00"` {
+		t.Errorf("unexpected output: %s", out)
+	}
+
+	val, err := NewIterator(out).ReadString()
+	if err != nil || val != "é😀" {
+		t.Errorf("round trip failed: %q, %v", val, err)
+	}
+}
+
+func TestWriteStringEscaped_HTML(t *testing.T) {
+	inputs := []string{
+		`</script><script>alert("x & y")</script>`,
+		"line\u2028sep\u2029para",
+		"plain ascii",
+		"€ uses the same lead byte as U+2028",
+	}
+
+	for _, input := range inputs {
+		expected, _ := json.Marshal(input)
+		out, err := MarshalWithOptions(input, EncodeOptions{EscapeHTML: true})
+		if err != nil {
+			t.Fatalf("Marshal failed: %v", err)
+		}
+		if string(out) != string(expected) {
+			t.Errorf("expected %s, got %s", expected, out)
+		}
+	}
+
+	out, _ := Marshal("<&>")
+	if string(out) != `"<&>"` {
+		t.Errorf("HTML escaping applied by default: %s", out)
+	}
+}
+
+func TestWriteStringEscaped_ASCIIOnly(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"plain", `"plain"`},
+		{"café", `"caf\u00e9"`},
+		{"日本", `"\u65e5\u672c"`},
+		{"a😀b", `"a\ud83d\ude00b"`},
+		{"bad \xff", `"bad \ufffd"`},
+		{"tab\t<", `"tab\t<"`},
+	}
+
+	for _, tt := range tests {
+		out, err := MarshalWithOptions(tt.input, EncodeOptions{ASCIIOnly: true})
+		if err != nil {
+			t.Fatalf("Marshal failed: %v", err)
+		}
+		if string(out) != tt.expected {
+			t.Errorf("expected %s, got %s", tt.expected, out)
+		}
+		for _, c := range out {
+			if c >= 0x80 {
+				t.Errorf("non-ASCII byte in output %q", out)
+				break
+			}
+		}
+	}
+}
+
+type oddKeys struct {
+	Quote int `json:"say \"hi\""`
+	Tag   int `json:"<b>"`
+	Cafe  int `json:"café"`
+	Plain int `json:"plain"`
+}
+
+func TestMarshal_EscapedStructKeys(t *testing.T) {
+	v := oddKeys{1, 2, 3, 4}
+	tests := []struct {
+		opts     EncodeOptions
+		expected string
+	}{
+		{EncodeOptions{}, `{"say \"hi\"":1,"<b>":2,"café":3,"plain":4}`},
+		{EncodeOptions{EscapeHTML: true}, `{"say \"hi\"":1,"\u003cb\u003e":2,"café":3,"plain":4}`},
+		{EncodeOptions{ASCIIOnly: true}, `{"say \"hi\"":1,"<b>":2,"caf\u00e9":3,"plain":4}`},
+		{EncodeOptions{Indent: " "}, "{\n \"say \\\"hi\\\"\": 1,\n \"<b>\": 2,\n \"café\": 3,\n \"plain\": 4\n}"},
+	}
+
+	for _, tt := range tests {
+		out, err := MarshalWithOptions(&v, tt.opts)
+		if err != nil {
+			t.Fatalf("Marshal failed: %v", err)
+		}
+		if string(out) != tt.expected {
+			t.Errorf("%+v: expected %s, got %s", tt.opts, tt.expected, out)
+		}
+	}
+
+	// The escaped keys still decode back into their fields.
+	out, _ := MarshalWithOptions(&v, EncodeOptions{EscapeHTML: true, ASCIIOnly: true})
+	var back oddKeys
+	if err := Unmarshal(out, &back); err != nil || back != v {
+		t.Errorf("round trip = %+v, %v", back, err)
+	}
+}
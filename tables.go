@@ -14,6 +14,18 @@ const (
 var (
 	parseTable  [256]byte
 	stringTable [256]byte
+
+	// utf8Table additionally flags every non-ASCII byte, for readers and
+	// writers that must inspect UTF-8 sequences. Strings that are pure ASCII
+	// take exactly the same path through either table.
+	utf8Table [256]byte
+
+	// htmlTable additionally flags '<', '>' and '&', plus 0xE2, the lead
+	// byte of U+2028 and U+2029, so that HTML-safe output keeps the same
+	// fast path for everything else. htmlUTF8Table combines it with
+	// utf8Table.
+	htmlTable     [256]byte
+	htmlUTF8Table [256]byte
 )
 
 func init() {
@@ -47,5 +59,20 @@ func init() {
 		if c == '"' || c == '\\' || c < 0x20 {
 			stringTable[i] = 1
 		}
+
+		utf8Table[i] = stringTable[i]
+		if c >= 0x80 {
+			utf8Table[i] = 1
+		}
+
+		htmlTable[i] = stringTable[i]
+		if c == '<' || c == '>' || c == '&' || c == 0xe2 {
+			htmlTable[i] = 1
+		}
+		htmlUTF8Table[i] = utf8Table[i] | htmlTable[i]
 	}
 }
+
+func isNumberStart(c byte) bool {
+	return c == '-' || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,25 @@
+package fastjson
+
+import (
+	"reflect"
+
+	"github.com/HeartBeat1608/fastjson/internal/structtag"
+)
+
+// fieldTag is the parsed form of a struct field's fastjson or json tag.
+type fieldTag = structtag.Field
+
+// parseTag reads field's tags; see structtag.Parse.
+func parseTag(field reflect.StructField, naming NamingStrategy) fieldTag {
+	return structtag.Parse(field.Name, field.Tag, naming)
+}
+
+// isQuotable reports whether the quoted option applies to t.
+func isQuotable(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int64, reflect.Int32, reflect.Float64, reflect.Bool:
+		return true
+	default:
+		return false
+	}
+}
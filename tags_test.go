@@ -0,0 +1,114 @@
+package fastjson
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+type RPCUser struct {
+	ID      int64  `json:"id" fastjson:",required,format=string"`
+	Name    string `json:"name" fastjson:"n,alias=name,alias=full_name"`
+	Secret  string `json:"-" fastjson:"secret"`
+	Public  string `json:"public" fastjson:"-"`
+	Role    string `json:"role" fastjson:"role,default=member"`
+	Enabled bool   `json:"enabled,string"`
+}
+
+func TestFastjsonTag_Marshal(t *testing.T) {
+	u := RPCUser{ID: 42, Name: "ann", Secret: "s", Public: "p", Role: "admin", Enabled: true}
+
+	got, err := Marshal(u)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	// Unlike encoding/json, the json tag's string option is ignored.
+	expected := `{"id":"42","n":"ann","secret":"s","role":"admin","enabled":true}`
+	if string(got) != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+
+	// encoding/json only sees the json tags.
+	std, _ := json.Marshal(u)
+	if string(std) != `{"id":42,"name":"ann","public":"p","role":"admin","enabled":"true"}` {
+		t.Errorf("unexpected encoding/json output %s", std)
+	}
+}
+
+func TestFastjsonTag_Unmarshal(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected RPCUser
+	}{
+		{"Name", `{"id": "1", "n": "a"}`, RPCUser{ID: 1, Name: "a", Role: "member"}},
+		{"Alias", `{"id": "2", "full_name": "b"}`, RPCUser{ID: 2, Name: "b", Role: "member"}},
+		{"Quoted", `{"id": "-3", "enabled": true, "role": "x"}`, RPCUser{ID: -3, Enabled: true, Role: "x"}},
+		{"Skipped", `{"id": "4", "public": "p", "secret": "s"}`, RPCUser{ID: 4, Secret: "s", Role: "member"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var u RPCUser
+			if err := Unmarshal([]byte(tt.input), &u); err != nil {
+				t.Fatalf("Unmarshal failed: %v", err)
+			}
+			if u != tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, u)
+			}
+		})
+	}
+}
+
+func TestFastjsonTag_Errors(t *testing.T) {
+	var u RPCUser
+
+	err := Unmarshal([]byte(`{"n": "a"}`), &u)
+	var reqErr *RequiredFieldError
+	if !errors.As(err, &reqErr) {
+		t.Fatalf("expected *RequiredFieldError, got %v", err)
+	}
+	if reqErr.Key != "id" || reqErr.Field != "ID" || reqErr.Offset != 10 {
+		t.Errorf("unexpected error fields: %+v", reqErr)
+	}
+
+	for _, input := range []string{`{"id": 5}`, `{"id": "5x"}`, `{"id": "\"5\""}`} {
+		var typeErr *UnmarshalTypeError
+		if err := Unmarshal([]byte(input), &u); !errors.As(err, &typeErr) {
+			t.Errorf("%s: expected *UnmarshalTypeError, got %v", input, err)
+		} else if typeErr.Field != "ID" {
+			t.Errorf("%s: expected field ID, got %q", input, typeErr.Field)
+		}
+	}
+
+	// The json tag's string option does not apply.
+	var typeErr *UnmarshalTypeError
+	if err := Unmarshal([]byte(`{"id": "6", "enabled": "true"}`), &u); !errors.As(err, &typeErr) || typeErr.Field != "Enabled" {
+		t.Errorf("expected *UnmarshalTypeError for Enabled, got %v", err)
+	}
+}
+
+func TestFastjsonTag_QuotedDecodesInPlace(t *testing.T) {
+	type quoted struct {
+		ID int64 `fastjson:"id,format=string"`
+	}
+	type plain struct {
+		ID int64 `json:"id"`
+	}
+
+	var q quoted
+	if err := Unmarshal([]byte(`{"id": "\u0034\u0032"}`), &q); err != nil || q.ID != 42 {
+		t.Errorf("escaped: got %d, %v", q.ID, err)
+	}
+	if err := Unmarshal([]byte(`{"id": "42"}`), &q); err != nil || q.ID != 42 {
+		t.Errorf("plain: got %d, %v", q.ID, err)
+	}
+
+	var p plain
+	in, quotedIn := []byte(`{"id": 42}`), []byte(`{"id": "42"}`)
+	want := testing.AllocsPerRun(100, func() { _ = Unmarshal(in, &p) })
+	got := testing.AllocsPerRun(100, func() { _ = Unmarshal(quotedIn, &q) })
+	if got != want {
+		t.Errorf("quoted field allocated %v times per run, want %v", got, want)
+	}
+}
@@ -0,0 +1,384 @@
+package fastjson
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"unsafe"
+)
+
+// union maps the values of a discriminator key to the concrete types an
+// interface type may hold.
+type union struct {
+	key     string
+	types   map[string]reflect.Type
+	byValue map[reflect.Type]string
+}
+
+// RegisterUnion lets every API decode objects into the interface type iface
+// by reading the discriminator key, which need not come first, and
+// decoding into variants[value]. Encoding a non-nil iface writes the
+// discriminator as the first key, unless the variant already has a field
+// with that key. Variants must be structs or pointers to structs that
+// implement iface; a nil variants removes the registration.
+//
+// A variant with a registered codec or MarshalFastJSON and
+// UnmarshalFastJSON methods keeps using them. Its encoder must write an
+// object, which the discriminator is added to, and its decoder is given
+// the whole object, discriminator included; a StructReader accepts the
+// discriminator as a known key.
+//
+// Like RegisterTypeEncoder, registering invalidates every cached codec.
+func RegisterUnion(iface reflect.Type, key string, variants map[string]reflect.Type) error {
+	return globalCodecs.registerUnion(iface, key, variants)
+}
+
+// RegisterUnion is like the package-level RegisterUnion but only affects a.
+// Like RegisterTypeEncoder, it panics on the shared package-level APIs.
+func (a *API) RegisterUnion(iface reflect.Type, key string, variants map[string]reflect.Type) error {
+	a.checkRegister()
+	return a.codecs.registerUnion(iface, key, variants)
+}
+
+func (c *typeCodecs) registerUnion(iface reflect.Type, key string, variants map[string]reflect.Type) error {
+	if iface.Kind() != reflect.Interface {
+		return fmt.Errorf("fastjson: cannot register union for non-interface type %s", iface)
+	}
+	if variants == nil {
+		c.unions.Delete(iface)
+		c.gen.Add(1)
+		return nil
+	}
+	if key == "" {
+		return fmt.Errorf("fastjson: union for %s needs a discriminator key", iface)
+	}
+
+	u := &union{
+		key:     key,
+		types:   make(map[string]reflect.Type, len(variants)),
+		byValue: make(map[reflect.Type]string, len(variants)),
+	}
+	for value, t := range variants {
+		st := t
+		if st.Kind() == reflect.Pointer {
+			st = st.Elem()
+		}
+		if st.Kind() != reflect.Struct {
+			return fmt.Errorf("fastjson: union variant %q of %s is %s, not a struct or pointer to struct", value, iface, t)
+		}
+		if !t.Implements(iface) {
+			return fmt.Errorf("fastjson: union variant %q: %s does not implement %s", value, t, iface)
+		}
+		if prev, ok := u.byValue[t]; ok {
+			return fmt.Errorf("fastjson: %s is registered as both %q and %q in union for %s", t, prev, value, iface)
+		}
+		u.types[value] = t
+		u.byValue[t] = value
+	}
+
+	c.unions.Store(iface, u)
+	c.gen.Add(1)
+	return nil
+}
+
+// registeredUnion returns the union registered for t, if any.
+func (a *API) registeredUnion(t reflect.Type) (*union, bool) {
+	if u, ok := a.codecs.unions.Load(t); ok {
+		return u.(*union), true
+	}
+	if u, ok := globalCodecs.unions.Load(t); ok {
+		return u.(*union), true
+	}
+	return nil, false
+}
+
+func (a *API) compileUnionEncoder(t reflect.Type, u *union) (EncoderFunc, error) {
+	encoders := make(map[reflect.Type]EncoderFunc, len(u.types))
+	for value, vt := range u.types {
+		var enc EncoderFunc
+		var err error
+		switch {
+		case a.hasFieldKey(vt, u.key):
+			enc, err = a.compileEncoder(vt)
+		case a.hasCustomEncoder(vt):
+			enc, err = a.compileEncoder(vt)
+			if err == nil {
+				enc = spliceDiscriminator(vt, enc, u.key, value)
+			}
+		default:
+			enc, err = a.compileTaggedEncoder(vt, u.key, value)
+		}
+		if err != nil {
+			return nil, err
+		}
+		encoders[vt] = enc
+	}
+
+	return func(w *Writer, p unsafe.Pointer) error {
+		rv := reflect.NewAt(t, p).Elem()
+		if rv.IsNil() {
+			w.WriteNull()
+			return nil
+		}
+
+		cv := rv.Elem()
+		enc, ok := encoders[cv.Type()]
+		if !ok {
+			return fmt.Errorf("fastjson: %s is not a registered variant of %s", cv.Type(), t)
+		}
+		ptr := reflect.New(cv.Type())
+		ptr.Elem().Set(cv)
+		return enc(w, ptr.UnsafePointer())
+	}, nil
+}
+
+// compileTaggedEncoder compiles the struct encoder of variant vt with the
+// discriminator as its first field.
+func (a *API) compileTaggedEncoder(vt reflect.Type, key, value string) (EncoderFunc, error) {
+	lead := structFieldEncoder{
+		encoder: func(w *Writer, _ unsafe.Pointer) error {
+			w.WriteStringEscaped(value)
+			return nil
+		},
+	}
+	lead.setName(key, true)
+
+	if vt.Kind() != reflect.Pointer {
+		return a.compileStructEncoder(vt, &lead)
+	}
+	enc, err := a.compileStructEncoder(vt.Elem(), &lead)
+	if err != nil {
+		return nil, err
+	}
+	return func(w *Writer, p unsafe.Pointer) error {
+		sp := *(*unsafe.Pointer)(p)
+		if sp == nil {
+			w.WriteNull()
+			return nil
+		}
+		return enc(w, sp)
+	}, nil
+}
+
+// spliceDiscriminator wraps enc, a registered encoder or MarshalFastJSON
+// method of variant vt, to add the discriminator after the '{' of what it
+// writes. Output that is not an object, other than null, is an
+// *UnsupportedValueError.
+func spliceDiscriminator(vt reflect.Type, enc EncoderFunc, key, value string) EncoderFunc {
+	return func(w *Writer, p unsafe.Pointer) error {
+		mark := len(w.Buffer)
+		if err := enc(w, p); err != nil {
+			return err
+		}
+		out := w.Buffer[mark:]
+		i := skipSpace(out)
+		if bytes.HasPrefix(out[i:], []byte("null")) {
+			return nil
+		}
+		if i == len(out) || out[i] != '{' {
+			return &UnsupportedValueError{
+				Value: reflect.NewAt(vt, p).Elem(),
+				Str:   fmt.Sprintf("%s does not encode as a JSON object, as union variants must", vt),
+			}
+		}
+
+		// Write the discriminator in place of what followed the '{', then
+		// put that back after it.
+		rest := append([]byte(nil), out[i+1:]...)
+		w.Buffer = w.Buffer[:mark+i+1]
+		if w.pretty {
+			w.level++
+			w.writeIndent()
+			w.WriteStringEscaped(key)
+			w.WriteString(": ")
+			w.WriteStringEscaped(value)
+			w.level--
+		} else {
+			w.WriteStringEscaped(key)
+			w.WriteByte(':')
+			w.WriteStringEscaped(value)
+		}
+
+		if j := skipSpace(rest); j < len(rest) && rest[j] == '}' {
+			// An empty object.
+			if w.pretty {
+				w.writeIndent()
+			}
+			w.Buffer = append(w.Buffer, rest[j:]...)
+		} else {
+			w.WriteByte(',')
+			w.Buffer = append(w.Buffer, rest...)
+		}
+		return nil
+	}
+}
+
+// skipSpace returns the index of the first byte of b that is not JSON
+// whitespace.
+func skipSpace(b []byte) int {
+	i := 0
+	for i < len(b) && parseTable[b[i]]&maskWhiteSpace != 0 {
+		i++
+	}
+	return i
+}
+
+// hasCustomEncoder reports whether variant t, or the struct it points to,
+// is encoded by a registered encoder or a MarshalFastJSON method rather
+// than as a plain struct.
+func (a *API) hasCustomEncoder(t reflect.Type) bool {
+	st := t
+	if st.Kind() == reflect.Pointer {
+		st = st.Elem()
+		if _, ok := a.registeredEncoder(t); ok {
+			return true
+		}
+	}
+	if _, ok := a.registeredEncoder(st); ok {
+		return true
+	}
+	return !a.ignoreMarshalers && reflect.PointerTo(st).Implements(marshalerType)
+}
+
+// hasCustomDecoder is the decoding counterpart of hasCustomEncoder.
+func (a *API) hasCustomDecoder(t reflect.Type) bool {
+	st := t
+	if st.Kind() == reflect.Pointer {
+		st = st.Elem()
+		if _, ok := a.registeredDecoder(t); ok {
+			return true
+		}
+	}
+	if _, ok := a.registeredDecoder(st); ok {
+		return true
+	}
+	return !a.ignoreMarshalers && reflect.PointerTo(st).Implements(unmarshalerType)
+}
+
+// hasFieldKey reports whether struct type t, or the struct t points to,
+// encodes a field under key.
+func (a *API) hasFieldKey(t reflect.Type, key string) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	for i := range t.NumField() {
+		if tag := parseTag(t.Field(i), a.naming); !tag.Skip && tag.Name == key {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *API) compileUnionDecoder(t reflect.Type, u *union) (DecoderFunc, error) {
+	type variantDecoder struct {
+		typ reflect.Type // type decoded into
+		ptr bool         // whether the variant is a pointer to typ
+		dec DecoderFunc
+	}
+
+	decoders := make(map[string]variantDecoder, len(u.types))
+	for value, vt := range u.types {
+		vd := variantDecoder{typ: vt}
+		var err error
+		if a.hasCustomDecoder(vt) {
+			// The variant decodes itself, and must accept the
+			// discriminator key.
+			vd.dec, err = a.getDecoder(vt)
+		} else {
+			if vt.Kind() == reflect.Pointer {
+				vd.typ, vd.ptr = vt.Elem(), true
+			}
+			vd.dec, err = a.compileStructDecoder(vd.typ, u.key)
+		}
+		if err != nil {
+			return nil, err
+		}
+		decoders[value] = vd
+	}
+
+	return func(it *Iterator, p unsafe.Pointer) error {
+		it.skipWhiteSpace()
+		switch it.char() {
+		case 'n':
+			if err := it.ReadNull(); err != nil {
+				return err
+			}
+			reflect.NewAt(t, p).Elem().SetZero()
+			return nil
+		case '{':
+		default:
+			return it.typeError(t)
+		}
+
+		value, found, err := it.peekKey(u.key)
+		if err != nil {
+			return err
+		}
+		vd, ok := decoders[value]
+		if !ok {
+			return &UnknownVariantError{Interface: t, Key: u.key, Value: value, Found: found, Offset: it.head}
+		}
+
+		// A StructReader beginning this very object learns the key, so
+		// that variants with generated decoders accept it.
+		it.discriminator, it.discriminatorAt = u.key, it.head
+		v := reflect.New(vd.typ)
+		err = vd.dec(it, v.UnsafePointer())
+		it.discriminator = ""
+		if err != nil {
+			return err
+		}
+		if !vd.ptr {
+			v = v.Elem()
+		}
+		reflect.NewAt(t, p).Elem().Set(v)
+		return nil
+	}, nil
+}
+
+// peekKey looks up the string value of key in the object at it.head
+// without consuming any input.
+func (it *Iterator) peekKey(key string) (value string, found bool, err error) {
+	peek := *it
+	peek.errs = nil
+	if err := peek.ReadObjectStart(); err != nil {
+		return "", false, err
+	}
+
+	peek.skipWhiteSpace()
+	if peek.char() == '}' {
+		return "", false, nil
+	}
+
+	for {
+		k, err := peek.ReadString()
+		if err != nil {
+			return "", false, err
+		}
+		if err := peek.ReadColon(); err != nil {
+			return "", false, err
+		}
+
+		if k == key {
+			peek.skipWhiteSpace()
+			if peek.char() != '"' {
+				return "", false, peek.typeError(stringType)
+			}
+			value, err := peek.ReadString()
+			return value, true, err
+		}
+		if err := peek.SkipValue(); err != nil {
+			return "", false, err
+		}
+
+		peek.skipWhiteSpace()
+		switch peek.char() {
+		case ',':
+			peek.head++
+		case '}':
+			return "", false, nil
+		default:
+			return "", false, peek.expected("',' or '}'")
+		}
+	}
+}
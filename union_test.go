@@ -0,0 +1,286 @@
+package fastjson
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type Event interface {
+	EventName() string
+}
+
+type ClickEvent struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+type KeyEvent struct {
+	Type string `json:"type"`
+	Key  string `json:"key"`
+}
+
+type PingEvent struct{}
+
+func (ClickEvent) EventName() string { return "click" }
+func (*KeyEvent) EventName() string  { return "key" }
+func (PingEvent) EventName() string  { return "ping" }
+
+type EventEnvelope struct {
+	ID     int     `json:"id"`
+	Event  Event   `json:"event"`
+	Others []Event `json:"others"`
+}
+
+func eventAPI(t *testing.T, c Config) *API {
+	t.Helper()
+	api := c.Freeze()
+	err := api.RegisterUnion(reflect.TypeFor[Event](), "type", map[string]reflect.Type{
+		"click": reflect.TypeFor[ClickEvent](),
+		"key":   reflect.TypeFor[*KeyEvent](),
+		"ping":  reflect.TypeFor[PingEvent](),
+	})
+	if err != nil {
+		t.Fatalf("RegisterUnion failed: %v", err)
+	}
+	return api
+}
+
+func TestUnion_Decode(t *testing.T) {
+	api := eventAPI(t, Config{})
+
+	input := `{"id": 1,
+		"event": {"x": 3, "y": 4, "type": "click"},
+		"others": [{"type": "key", "key": "q"}, {"type": "ping"}, null]}`
+
+	var env EventEnvelope
+	if err := api.Unmarshal([]byte(input), &env); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	expected := EventEnvelope{
+		ID:     1,
+		Event:  ClickEvent{X: 3, Y: 4},
+		Others: []Event{&KeyEvent{Type: "key", Key: "q"}, PingEvent{}, nil},
+	}
+	if !reflect.DeepEqual(env, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, env)
+	}
+
+	// The discriminator is not an unknown field.
+	strict := eventAPI(t, Config{Decode: DecodeOptions{DisallowUnknownFields: true}})
+	if err := strict.Unmarshal([]byte(input), &env); err != nil {
+		t.Errorf("Unmarshal failed: %v", err)
+	}
+}
+
+func TestUnion_Encode(t *testing.T) {
+	api := eventAPI(t, Config{})
+	env := EventEnvelope{
+		ID:     1,
+		Event:  ClickEvent{X: 3, Y: 4},
+		Others: []Event{&KeyEvent{Type: "key", Key: "q"}, PingEvent{}, (*KeyEvent)(nil), nil},
+	}
+
+	got, err := api.Marshal(env)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	expected := `{"id":1,"event":{"type":"click","x":3,"y":4},` +
+		`"others":[{"type":"key","key":"q"},{"type":"ping"},null,null]}`
+	if string(got) != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+
+	var back EventEnvelope
+	if err := api.Unmarshal(got, &back); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	got, err = api.MarshalIndent(EventEnvelope{Event: ClickEvent{X: 1}, Others: []Event{PingEvent{}}}, "", "  ")
+	if err != nil {
+		t.Fatalf("MarshalIndent failed: %v", err)
+	}
+	expected = `{
+  "id": 0,
+  "event": {
+    "type": "click",
+    "x": 1,
+    "y": 0
+  },
+  "others": [
+    {
+      "type": "ping"
+    }
+  ]
+}`
+	if string(got) != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, got)
+	}
+
+	// Without the union, interfaces with methods encode their dynamic value.
+	got, err = Marshal(env)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if string(got) != `{"id":1,"event":{"x":3,"y":4},"others":[{"type":"key","key":"q"},{},null,null]}` {
+		t.Errorf("unexpected output %s", got)
+	}
+}
+
+func TestUnion_Errors(t *testing.T) {
+	api := eventAPI(t, Config{})
+
+	var env EventEnvelope
+	for _, input := range []string{`{"event": {"x": 1}}`, `{"event": {"type": "scroll"}}`} {
+		var variantErr *UnknownVariantError
+		if err := api.Unmarshal([]byte(input), &env); !errors.As(err, &variantErr) {
+			t.Errorf("%s: expected *UnknownVariantError, got %v", input, err)
+		}
+	}
+
+	var typeErr *UnmarshalTypeError
+	if err := api.Unmarshal([]byte(`{"event": [1]}`), &env); !errors.As(err, &typeErr) {
+		t.Errorf("expected *UnmarshalTypeError, got %v", err)
+	}
+
+	// Without the union, only null can be decoded into an Event.
+	if err := Unmarshal([]byte(`{"event": {"type": "click"}}`), &env); !errors.As(err, &typeErr) {
+		t.Errorf("expected *UnmarshalTypeError, got %v", err)
+	}
+	if err := Unmarshal([]byte(`{"event": null}`), &env); err != nil || env.Event != nil {
+		t.Errorf("expected nil event, got %v (err %v)", env.Event, err)
+	}
+
+	// ...or a pointer it already holds.
+	key := &KeyEvent{}
+	env.Event = key
+	if err := Unmarshal([]byte(`{"event": {"key": "z"}}`), &env); err != nil || env.Event != Event(key) || key.Key != "z" {
+		t.Errorf("expected held pointer to be decoded into, got %#v (err %v)", env.Event, err)
+	}
+
+	err := api.RegisterUnion(reflect.TypeFor[Event](), "type", map[string]reflect.Type{
+		"click": reflect.TypeFor[KeyEvent](), // only *KeyEvent implements Event
+	})
+	if err == nil {
+		t.Error("expected non-implementing variant to be rejected")
+	}
+}
+
+// ScrollEvent encodes and decodes itself, as generated code does.
+type ScrollEvent struct {
+	Delta   int
+	decoded bool // set by UnmarshalFastJSON
+}
+
+func (*ScrollEvent) EventName() string { return "scroll" }
+
+func (e *ScrollEvent) MarshalFastJSON(w *Writer) error {
+	w.ObjectStart()
+	w.ObjectKey(`"delta"`, true)
+	w.WriteInt64(int64(e.Delta))
+	w.ObjectEnd()
+	return nil
+}
+
+func (e *ScrollEvent) UnmarshalFastJSON(it *Iterator) error {
+	var r StructReader
+	if err := r.Begin(it, reflect.TypeFor[ScrollEvent](), 1, false); err != nil {
+		return err
+	}
+	for {
+		key, ok, err := r.Next()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+		if key != "delta" {
+			if err := r.Unknown(); err != nil {
+				return err
+			}
+		} else if ok, err := r.Field(0); err != nil {
+			return err
+		} else if ok {
+			if err := r.Done(it.DecodeInt(&e.Delta), "Delta"); err != nil {
+				return err
+			}
+		}
+	}
+	e.decoded = true
+	return nil
+}
+
+// BlankEvent writes an empty object after some whitespace.
+type BlankEvent struct{}
+
+func (BlankEvent) EventName() string { return "blank" }
+
+func (*BlankEvent) MarshalFastJSON(w *Writer) error {
+	w.WriteString(" {}")
+	return nil
+}
+
+// OddEvent does not encode as an object at all.
+type OddEvent struct{}
+
+func (OddEvent) EventName() string { return "odd" }
+
+func (*OddEvent) MarshalFastJSON(w *Writer) error {
+	w.WriteString(`"odd"`)
+	return nil
+}
+
+func TestUnion_CustomVariants(t *testing.T) {
+	api := Config{}.Freeze()
+	err := api.RegisterUnion(reflect.TypeFor[Event](), "type", map[string]reflect.Type{
+		"scroll": reflect.TypeFor[*ScrollEvent](),
+		"blank":  reflect.TypeFor[BlankEvent](),
+		"odd":    reflect.TypeFor[OddEvent](),
+	})
+	if err != nil {
+		t.Fatalf("RegisterUnion failed: %v", err)
+	}
+
+	events := []Event{&ScrollEvent{Delta: 3}, BlankEvent{}}
+	got, err := api.Marshal(events)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	expected := `[{"type":"scroll","delta":3}, {"type":"blank"}]`
+	if string(got) != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+
+	got, err = api.MarshalIndent(events, "", "  ")
+	if err != nil {
+		t.Fatalf("MarshalIndent failed: %v", err)
+	}
+	expected = `[
+  {
+    "type": "scroll",
+    "delta": 3
+  },
+   {
+    "type": "blank"
+  }
+]`
+	if string(got) != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, got)
+	}
+
+	// Decoding goes through UnmarshalFastJSON, which sees the discriminator.
+	var back []Event
+	if err := api.Unmarshal([]byte(`[{"delta": 5, "type": "scroll"}]`), &back); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if e, ok := back[0].(*ScrollEvent); !ok || e.Delta != 5 || !e.decoded {
+		t.Errorf("unexpected event %#v", back[0])
+	}
+
+	var valueErr *UnsupportedValueError
+	if _, err := api.Marshal([]Event{OddEvent{}}); !errors.As(err, &valueErr) {
+		t.Errorf("expected *UnsupportedValueError, got %v", err)
+	}
+}
@@ -1,23 +1,52 @@
 package fastjson
 
 import (
+	"errors"
 	"reflect"
 	"unsafe"
 )
 
 func Unmarshal(data []byte, v any) error {
-	it := NewIterator(data)
+	return defaultAPI.unmarshal(data, v, DecodeOptions{})
+}
+
+// UnmarshalWithOptions is like Unmarshal but applies opts while decoding.
+func UnmarshalWithOptions(data []byte, v any, opts DecodeOptions) error {
+	return defaultAPI.unmarshal(data, v, opts)
+}
 
+func (a *API) unmarshal(data []byte, v any, opts DecodeOptions) error {
 	rv := reflect.ValueOf(v)
 	if rv.Kind() != reflect.Pointer || rv.IsNil() {
-		return it.error("Unmarshal(non-pointer or nil)")
+		return &InvalidUnmarshalError{Type: reflect.TypeOf(v)}
 	}
+	return a.unmarshalAt(data, rv.Elem().Type(), unsafe.Pointer(rv.Pointer()), opts)
+}
 
-	dec, err := getDecoder(rv.Elem().Type())
+// unmarshalAt decodes data into the value of type t at p.
+func (a *API) unmarshalAt(data []byte, t reflect.Type, p unsafe.Pointer, opts DecodeOptions) error {
+	dec, err := a.getDecoder(t)
 	if err != nil {
 		return err
 	}
+	return a.decodeWith(data, dec, p, opts)
+}
+
+// decodeWith decodes data into the value at p using dec.
+func (a *API) decodeWith(data []byte, dec DecoderFunc, p unsafe.Pointer, opts DecodeOptions) error {
+	it := NewIterator(data)
+	it.SetOptions(opts)
+	it.api = a
+	if err := it.checkInputSize(); err != nil {
+		return err
+	}
 
-	ptr := unsafe.Pointer(rv.Pointer())
-	return dec(it, ptr)
+	err := dec(it, p)
+	if err == nil && it.opts.Strict {
+		err = it.checkEnd()
+	}
+	if len(it.errs) > 0 {
+		return errors.Join(append(it.errs, err)...)
+	}
+	return err
 }
@@ -0,0 +1,233 @@
+package fastjson
+
+// Valid reports whether data is a single valid JSON value as defined by
+// RFC 8259, optionally surrounded by whitespace.
+func Valid(data []byte) bool {
+	return Validate(data) == nil
+}
+
+// Validate is like Valid but returns a *SyntaxError describing the first
+// problem found.
+func Validate(data []byte) error {
+	it := NewIterator(data)
+	it.opts.Strict = true
+	if err := it.skipStrict(); err != nil {
+		return err
+	}
+	return it.checkEnd()
+}
+
+// checkEnd rejects anything but whitespace after the top-level value.
+func (it *Iterator) checkEnd() error {
+	if !it.atEnd() {
+		return it.error("unexpected data after top-level value")
+	}
+	return nil
+}
+
+// atEnd reports whether only whitespace is left in the input.
+func (it *Iterator) atEnd() bool {
+	it.skipWhiteSpace()
+	return it.head >= it.dataLen
+}
+
+// rejectTrailingComma fails in strict mode when the member after a comma is
+// missing, as in `[1,]`.
+func (it *Iterator) rejectTrailingComma(end byte) error {
+	if it.opts.Strict {
+		it.skipWhiteSpace()
+		if it.char() == end {
+			return it.expected("value")
+		}
+	}
+	return nil
+}
+
+// skipStrict consumes one value, enforcing the full RFC 8259 grammar.
+func (it *Iterator) skipStrict() error {
+	it.skipWhiteSpace()
+	if it.head >= it.dataLen {
+		return it.expected("value")
+	}
+
+	switch c := it.data[it.head]; c {
+	case '"':
+		return it.skipStringStrict()
+	case '{':
+		return it.skipObjectStrict()
+	case '[':
+		return it.skipArrayStrict()
+	case 't':
+		return it.skipLiteral("true")
+	case 'f':
+		return it.skipLiteral("false")
+	case 'n':
+		return it.skipLiteral("null")
+	default:
+		if isNumberStart(c) {
+			return it.skipNumberStrict()
+		}
+		return it.expected("value")
+	}
+}
+
+func (it *Iterator) skipObjectStrict() error {
+	if err := it.ReadObjectStart(); err != nil {
+		return err
+	}
+
+	it.skipWhiteSpace()
+	if it.char() == '}' {
+		it.head++
+		it.depth--
+		return nil
+	}
+
+	for {
+		it.skipWhiteSpace()
+		if it.char() != '"' {
+			return it.expected("string")
+		}
+		if err := it.skipStringStrict(); err != nil {
+			return err
+		}
+		if err := it.ReadColon(); err != nil {
+			return err
+		}
+		if err := it.skipStrict(); err != nil {
+			return err
+		}
+
+		it.skipWhiteSpace()
+		switch it.char() {
+		case ',':
+			it.head++
+		case '}':
+			it.head++
+			it.depth--
+			return nil
+		default:
+			return it.expected("',' or '}'")
+		}
+	}
+}
+
+func (it *Iterator) skipArrayStrict() error {
+	if err := it.ReadArrayStart(); err != nil {
+		return err
+	}
+
+	it.skipWhiteSpace()
+	if it.char() == ']' {
+		it.head++
+		it.depth--
+		return nil
+	}
+
+	for {
+		if err := it.skipStrict(); err != nil {
+			return err
+		}
+
+		it.skipWhiteSpace()
+		switch it.char() {
+		case ',':
+			it.head++
+		case ']':
+			it.head++
+			it.depth--
+			return nil
+		default:
+			return it.expected("',' or ']'")
+		}
+	}
+}
+
+func (it *Iterator) skipLiteral(lit string) error {
+	if it.head+len(lit) <= it.dataLen && bytesToString(it.data[it.head:it.head+len(lit)]) == lit {
+		it.head += len(lit)
+		return nil
+	}
+	return it.expected("'" + lit + "'")
+}
+
+func (it *Iterator) skipStringStrict() error {
+	it.head++ // opening quote
+	for it.head < it.dataLen {
+		c := it.data[it.head]
+		if stringTable[c] == 0 {
+			it.head++
+			continue
+		}
+
+		switch {
+		case c == '"':
+			it.head++
+			return nil
+		case c == '\\':
+			it.head++
+			switch it.char() {
+			case '"', '\\', '/', 'b', 'f', 'n', 'r', 't':
+				it.head++
+			case 'u':
+				if it.head+4 >= it.dataLen {
+					return it.error("incomplete unicode escape")
+				}
+				if _, err := it.decodeUnicode(); err != nil {
+					return err
+				}
+				it.head += 5
+			default:
+				return it.error("invalid escape sequence")
+			}
+		default:
+			return it.error("control character in string")
+		}
+	}
+	return it.error("unexpected end of input in string")
+}
+
+// skipNumberStrict consumes a number matching
+//
+//	-? (0 | [1-9][0-9]*) (\.[0-9]+)? ([eE][+-]?[0-9]+)?
+func (it *Iterator) skipNumberStrict() error {
+	if it.char() == '-' {
+		it.head++
+	}
+
+	switch c := it.char(); {
+	case c == '0':
+		it.head++
+	case c >= '1' && c <= '9':
+		it.skipDigits()
+	default:
+		return it.expected("digit")
+	}
+
+	if it.char() == '.' {
+		it.head++
+		if !it.skipDigits() {
+			return it.expected("digit")
+		}
+	}
+
+	if c := it.char(); c == 'e' || c == 'E' {
+		it.head++
+		if c := it.char(); c == '+' || c == '-' {
+			it.head++
+		}
+		if !it.skipDigits() {
+			return it.expected("digit")
+		}
+	}
+	return nil
+}
+
+// skipDigits consumes a run of decimal digits, reporting whether there was one.
+func (it *Iterator) skipDigits() bool {
+	start := it.head
+	for it.head < it.dataLen && it.data[it.head] >= '0' && it.data[it.head] <= '9' {
+		it.head++
+	}
+	return it.head > start
+}
@@ -0,0 +1,96 @@
+package fastjson
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestValid(t *testing.T) {
+	tests := []struct {
+		input string
+		valid bool
+	}{
+		{`{}`, true},
+		{`[]`, true},
+		{` {"a": [1, -2.5e+3, "xé\n", true, false, null, {}]} `, true},
+		{`0`, true},
+		{`-0.0E-0`, true},
+		{`"\/\b\f\r\t\\\""`, true},
+		{``, false},
+		{` `, false},
+		{`tru`, false},
+		{`nul`, false},
+		{`truex`, false},
+		{`1.2.3`, false},
+		{`01`, false},
+		{`+1`, false},
+		{`1.`, false},
+		{`.5`, false},
+		{`1e`, false},
+		{`-`, false},
+		{`[1,]`, false},
+		{`{"a":1,}`, false},
+		{`{"a" 1}`, false},
+		{`{1: 2}`, false},
+		{`[1 2]`, false},
+		{`"\x"`, false},
+		{`"\u12"`, false},
+		{"\"\t\"", false},
+		{`{} {}`, false},
+		{`[}`, false},
+		{`"unterminated`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := Valid([]byte(tt.input)); got != tt.valid {
+				t.Errorf("Valid(%q) = %v, want %v (err: %v)", tt.input, got, tt.valid, Validate([]byte(tt.input)))
+			}
+			if std := json.Valid([]byte(tt.input)); std != tt.valid {
+				t.Errorf("test case disagrees with encoding/json: %v", std)
+			}
+		})
+	}
+}
+
+func TestUnmarshal_Strict(t *testing.T) {
+	strict := DecodeOptions{Strict: true}
+
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"TrailingGarbage", `{"id": 1} x`},
+		{"SkippedLiteral", `{"id": 1, "extra": tru}`},
+		{"SkippedNumber", `{"id": 1, "extra": 1.2.3}`},
+		{"TrailingComma", `{"id": 1,}`},
+		{"Float", `{"Balance": 1.}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var u User
+			if err := Unmarshal([]byte(tt.input), &u); err != nil {
+				t.Fatalf("lenient Unmarshal failed: %v", err)
+			}
+			if err := UnmarshalWithOptions([]byte(tt.input), &u, strict); err == nil {
+				t.Errorf("expected strict Unmarshal to fail")
+			}
+		})
+	}
+
+	var u User
+	if err := UnmarshalWithOptions([]byte(` {"id": 1, "extra": [null, {"a": 1e5}]} `), &u, strict); err != nil {
+		t.Errorf("unexpected error for valid input: %v", err)
+	}
+}
+
+func TestUnmarshal_Null(t *testing.T) {
+	var v any = "previous"
+	if err := Unmarshal([]byte(`null`), &v); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if v != nil {
+		t.Errorf("expected nil, got %v", v)
+	}
+}
@@ -1,12 +1,25 @@
 package fastjson
 
 import (
+	"math"
 	"strconv"
 	"sync"
+	"unicode/utf16"
+	"unicode/utf8"
 )
 
 type Writer struct {
 	Buffer []byte
+
+	opts  EncodeOptions
+	table *[256]byte // string escaping fast path; see SetOptions
+	err   error
+	api   *API // the API encoding through this Writer; nil means the default
+
+	// pretty is set when opts asks for indentation; level is the current
+	// nesting depth of indented output.
+	pretty bool
+	level  int
 }
 
 var hexChars = "0123456789abcdef"
@@ -20,6 +33,9 @@ var writerPool = sync.Pool{
 func GetWriter() *Writer {
 	w := writerPool.Get().(*Writer)
 	w.Buffer = w.Buffer[:0]
+	w.SetOptions(EncodeOptions{})
+	w.err = nil
+	w.api = nil
 	return w
 }
 
@@ -27,12 +43,21 @@ func PutWriter(w *Writer) {
 	writerPool.Put(w)
 }
 
+// Err returns the first error recorded by a write that cannot fail inline,
+// such as a string rejected under UTF8Reject.
+func (w *Writer) Err() error {
+	return w.err
+}
+
 func (w *Writer) Write(p []byte) {
 	w.Buffer = append(w.Buffer, p...)
 }
 
-func (w *Writer) WriteByte(c byte) {
+// WriteByte appends a single byte. It never fails; the error result only
+// exists to satisfy io.ByteWriter.
+func (w *Writer) WriteByte(c byte) error {
 	w.Buffer = append(w.Buffer, c)
+	return nil
 }
 
 func (w *Writer) WriteString(s string) {
@@ -40,18 +65,37 @@ func (w *Writer) WriteString(s string) {
 }
 
 // WriteStringEscaped writes a string with JSON quoting and escaping.
-// It uses the Look-up Table (stringTable) to optimize the 'happy path'.
+// Bytes that need no escaping are copied in runs, using the look-up table
+// that SetOptions picked for the Writer's options: stringTable by default,
+// htmlTable under EscapeHTML, and utf8Table or htmlUTF8Table when non-ASCII
+// bytes must be inspected for InvalidUTF8, EscapeNonBMP or ASCIIOnly.
+// Invalid UTF-8 is then copied, replaced with \ufffd or, under UTF8Reject,
+// recorded as an *InvalidUTF8Error that Err returns.
 func (w *Writer) WriteStringEscaped(s string) {
+	table := w.table
+	if table == nil {
+		table = &stringTable
+	}
+
 	w.Buffer = append(w.Buffer, '"')
 	start := 0
-	for i := 0; i < len(s); i++ {
-		if stringTable[s[i]] != 0 {
-			if i > start {
-				w.Buffer = append(w.Buffer, s[start:i]...)
-			}
-			w.writeEscapedChar(s[i])
-			start = i + 1
+	for i := 0; i < len(s); {
+		c := s[i]
+		if table[c] == 0 {
+			i++
+			continue
 		}
+
+		if i > start {
+			w.Buffer = append(w.Buffer, s[start:i]...)
+		}
+		if c < utf8.RuneSelf {
+			w.writeEscapedChar(c)
+			i++
+		} else {
+			i += w.writeRune(s, i)
+		}
+		start = i
 	}
 
 	if start < len(s) {
@@ -61,6 +105,47 @@ func (w *Writer) WriteStringEscaped(s string) {
 	w.Buffer = append(w.Buffer, '"')
 }
 
+// writeRune writes the UTF-8 sequence at s[i], which begins with a
+// non-ASCII byte, and returns how many bytes it consumed.
+func (w *Writer) writeRune(s string, i int) int {
+	r, size := utf8.DecodeRuneInString(s[i:])
+	if r == utf8.RuneError && size == 1 {
+		switch w.opts.InvalidUTF8 {
+		case UTF8Reject:
+			if w.err == nil {
+				w.err = &InvalidUTF8Error{Str: s, Offset: i}
+			}
+		case UTF8Replace:
+			w.Buffer = append(w.Buffer, `\ufffd`...)
+			return 1
+		}
+	}
+
+	// Under ASCIIOnly this also covers invalid bytes that were let through,
+	// which come out as \ufffd since they cannot be represented otherwise.
+	if w.opts.ASCIIOnly || (r > 0xffff && w.opts.EscapeNonBMP) ||
+		((r == '\u2028' || r == '\u2029') && w.opts.EscapeHTML) {
+		w.writeUnicodeEscape(r)
+		return size
+	}
+
+	w.Buffer = append(w.Buffer, s[i:i+size]...)
+	return size
+}
+
+// writeUnicodeEscape writes r as \uXXXX, using a surrogate pair for
+// characters outside the Basic Multilingual Plane.
+func (w *Writer) writeUnicodeEscape(r rune) {
+	if r > 0xffff {
+		r1, r2 := utf16.EncodeRune(r)
+		w.writeUnicodeEscape(r1)
+		w.writeUnicodeEscape(r2)
+		return
+	}
+	w.Buffer = append(w.Buffer, '\\', 'u',
+		hexChars[r>>12&0xf], hexChars[r>>8&0xf], hexChars[r>>4&0xf], hexChars[r&0xf])
+}
+
 func (w *Writer) writeEscapedChar(c byte) {
 	switch c {
 	case '"':
@@ -78,12 +163,9 @@ func (w *Writer) writeEscapedChar(c byte) {
 	case '\t':
 		w.Buffer = append(w.Buffer, '\\', 't')
 	default:
-		if c < 0x20 {
-			w.Buffer = append(w.Buffer, '\\', 'u', '0', '0')
-			w.Buffer = append(w.Buffer, hexChars[c>>4], hexChars[c&0xf])
-		} else {
-			w.Buffer = append(w.Buffer, c)
-		}
+		// Other control characters, and '<', '>' and '&' under EscapeHTML.
+		w.Buffer = append(w.Buffer, '\\', 'u', '0', '0')
+		w.Buffer = append(w.Buffer, hexChars[c>>4], hexChars[c&0xf])
 	}
 }
 
@@ -95,12 +177,53 @@ func (w *Writer) WriteUint64(n uint64) {
 	w.Buffer = strconv.AppendUint(w.Buffer, n, 10)
 }
 
+// WriteFloat64 writes n the way encoding/json does: in decimal notation,
+// switching to exponent notation outside [1e-6, 1e21). NaN and infinities
+// are handled according to EncodeOptions.NonFinite.
 func (w *Writer) WriteFloat64(n float64) {
-	b := w.Buffer
-	b = strconv.AppendFloat(b, n, 'f', -1, 64)
+	if math.IsNaN(n) || math.IsInf(n, 0) {
+		w.writeNonFinite(n)
+		return
+	}
+
+	abs := math.Abs(n)
+	format := byte('f')
+	if abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		format = 'e'
+	}
+
+	b := strconv.AppendFloat(w.Buffer, n, format, -1, 64)
+	if format == 'e' {
+		// Shorten e-09 to e-9, as encoding/json does.
+		if l := len(b); l >= 4 && b[l-4] == 'e' && b[l-3] == '-' && b[l-2] == '0' {
+			b[l-2] = b[l-1]
+			b = b[:l-1]
+		}
+	}
 	w.Buffer = b
 }
 
+func (w *Writer) writeNonFinite(n float64) {
+	switch w.opts.NonFinite {
+	case NonFiniteString:
+		switch {
+		case math.IsNaN(n):
+			w.WriteString(`"NaN"`)
+		case n > 0:
+			w.WriteString(`"Infinity"`)
+		default:
+			w.WriteString(`"-Infinity"`)
+		}
+	case NonFiniteNull:
+		w.WriteNull()
+	default:
+		if w.err == nil {
+			w.err = unsupportedFloat(n)
+		}
+		w.WriteNull()
+	}
+}
+
 func (w *Writer) WriteBool(b bool) {
 	if b {
 		w.Buffer = append(w.Buffer, "true"...)
@@ -112,3 +235,110 @@ func (w *Writer) WriteBool(b bool) {
 func (w *Writer) WriteNull() {
 	w.Buffer = append(w.Buffer, "null"...)
 }
+
+// openIndent starts an indented object or array.
+func (w *Writer) openIndent(c byte) {
+	w.Buffer = append(w.Buffer, c)
+	w.level++
+}
+
+// closeIndent ends an indented object or array on a line of its own.
+func (w *Writer) closeIndent(c byte) {
+	w.level--
+	w.writeIndent()
+	w.Buffer = append(w.Buffer, c)
+}
+
+// writeIndent starts a new line at the current nesting depth.
+func (w *Writer) writeIndent() {
+	w.Buffer = append(w.Buffer, '\n')
+	w.Buffer = append(w.Buffer, w.opts.Prefix...)
+	for range w.level {
+		w.Buffer = append(w.Buffer, w.opts.Indent...)
+	}
+}
+
+// The methods below write the structure of objects and arrays for
+// hand-written and generated MarshalFastJSON methods, laid out as compiled
+// encoders would under the Writer's options. They expect at least one
+// member; empty objects and arrays are written whole, as {} and [].
+
+// ObjectStart opens an object.
+func (w *Writer) ObjectStart() {
+	if w.pretty {
+		w.openIndent('{')
+	} else {
+		w.Buffer = append(w.Buffer, '{')
+	}
+}
+
+// ObjectKey writes key, which must already be quoted and escaped, and the
+// colon after it. first tells whether it is the object's first key.
+func (w *Writer) ObjectKey(key string, first bool) {
+	if !first {
+		w.Buffer = append(w.Buffer, ',')
+	}
+	if w.pretty {
+		w.writeIndent()
+		w.Buffer = append(w.Buffer, key...)
+		w.Buffer = append(w.Buffer, ": "...)
+	} else {
+		w.Buffer = append(w.Buffer, key...)
+		w.Buffer = append(w.Buffer, ':')
+	}
+}
+
+// ObjectKeyEscaped is like ObjectKey for a key that is not yet quoted. It
+// is escaped as w's options ask, as compiled encoders do for field names
+// with characters that need it.
+func (w *Writer) ObjectKeyEscaped(key string, first bool) {
+	if !first {
+		w.Buffer = append(w.Buffer, ',')
+	}
+	if w.pretty {
+		w.writeIndent()
+		w.WriteStringEscaped(key)
+		w.Buffer = append(w.Buffer, ": "...)
+	} else {
+		w.WriteStringEscaped(key)
+		w.Buffer = append(w.Buffer, ':')
+	}
+}
+
+// ObjectEnd closes an object.
+func (w *Writer) ObjectEnd() {
+	if w.pretty {
+		w.closeIndent('}')
+	} else {
+		w.Buffer = append(w.Buffer, '}')
+	}
+}
+
+// ArrayStart opens an array.
+func (w *Writer) ArrayStart() {
+	if w.pretty {
+		w.openIndent('[')
+	} else {
+		w.Buffer = append(w.Buffer, '[')
+	}
+}
+
+// ArrayElem prepares for an element. first tells whether it is the
+// array's first element.
+func (w *Writer) ArrayElem(first bool) {
+	if !first {
+		w.Buffer = append(w.Buffer, ',')
+	}
+	if w.pretty {
+		w.writeIndent()
+	}
+}
+
+// ArrayEnd closes an array.
+func (w *Writer) ArrayEnd() {
+	if w.pretty {
+		w.closeIndent(']')
+	} else {
+		w.Buffer = append(w.Buffer, ']')
+	}
+}